@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/cloudquery/plugin-sdk/specs"
+)
+
+const checkpointsDir = "checkpoints"
+
+// checkpointOptions configures checkpointing of a sync. A nil *checkpointOptions disables checkpointing.
+type checkpointOptions struct {
+	// resume continues the sync recorded in an existing checkpoint, if there is one
+	resume bool
+	// batchSize is the number of top-level tables synced between checkpoints
+	batchSize int
+}
+
+// syncCheckpoint records the top-level tables of a source that were synced to all destinations, so that an
+// interrupted sync can be resumed without syncing them again.
+type syncCheckpoint struct {
+	InvocationUUID  string    `json:"invocation_uuid"`
+	SpecHash        string    `json:"spec_hash"`
+	SyncTime        time.Time `json:"sync_time"`
+	CompletedTables []string  `json:"completed_tables"`
+
+	path string
+}
+
+// sourceSpecHash returns a hash of the source spec, so that a checkpoint is only resumed with the same spec
+func sourceSpecHash(sourceSpec specs.Source) (string, error) {
+	b, err := json.Marshal(sourceSpec)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal source spec: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func checkpointPath(cqDir string, sourceName string, specHash string) string {
+	return filepath.Join(cqDir, checkpointsDir, fmt.Sprintf("%s-%s.json", sourceName, specHash[:16]))
+}
+
+func newSyncCheckpoint(cqDir string, sourceSpec specs.Source, uid string, syncTime time.Time) (*syncCheckpoint, error) {
+	specHash, err := sourceSpecHash(sourceSpec)
+	if err != nil {
+		return nil, err
+	}
+	return &syncCheckpoint{
+		InvocationUUID:  uid,
+		SpecHash:        specHash,
+		SyncTime:        syncTime,
+		CompletedTables: []string{},
+		path:            checkpointPath(cqDir, sourceSpec.Name, specHash),
+	}, nil
+}
+
+// loadSyncCheckpoint returns the checkpoint of the source spec, or nil if there is none
+func loadSyncCheckpoint(cqDir string, sourceSpec specs.Source) (*syncCheckpoint, error) {
+	specHash, err := sourceSpecHash(sourceSpec)
+	if err != nil {
+		return nil, err
+	}
+	path := checkpointPath(cqDir, sourceSpec.Name, specHash)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint %s: %w", path, err)
+	}
+	var checkpoint syncCheckpoint
+	if err := json.Unmarshal(b, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+	if checkpoint.SpecHash != specHash {
+		return nil, fmt.Errorf("checkpoint %s was recorded for a different spec of source %s", path, sourceSpec.Name)
+	}
+	checkpoint.path = path
+	return &checkpoint, nil
+}
+
+func (c *syncCheckpoint) isCompleted(table string) bool {
+	for _, t := range c.CompletedTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// complete marks the tables as synced and saves the checkpoint
+func (c *syncCheckpoint) complete(tables schema.Tables) error {
+	for _, table := range tables {
+		c.CompletedTables = append(c.CompletedTables, table.Name)
+	}
+	return c.save()
+}
+
+// save writes the checkpoint to a temporary file first, so that a sync killed while saving doesn't leave a
+// truncated checkpoint behind.
+func (c *syncCheckpoint) save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoints directory: %w", err)
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, b, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return fmt.Errorf("failed to save checkpoint %s: %w", c.path, err)
+	}
+	return nil
+}
+
+func (c *syncCheckpoint) remove() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove checkpoint %s: %w", c.path, err)
+	}
+	return nil
+}
+
+// checkpointBatches splits the top-level tables that weren't completed yet into batches of batchSize tables
+func checkpointBatches(tables schema.Tables, checkpoint *syncCheckpoint, batchSize int) []schema.Tables {
+	var batches []schema.Tables
+	var batch schema.Tables
+	for _, table := range tables {
+		if checkpoint.isCompleted(table.Name) {
+			continue
+		}
+		batch = append(batch, table)
+		if len(batch) == batchSize {
+			batches = append(batches, batch)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// batchSourceSpec returns the source spec that syncs exactly the tables of the batch. The batch was already
+// filtered by the original spec, so all of its tables, including relations, are listed explicitly.
+func batchSourceSpec(sourceSpec specs.Source, batch schema.Tables) specs.Source {
+	batchSpec := sourceSpec
+	batchSpec.Tables = batch.TableNames()
+	batchSpec.SkipTables = nil
+	batchSpec.SkipDependentTables = true
+	return batchSpec
+}
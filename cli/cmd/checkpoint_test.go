@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncCheckpoint(t *testing.T) {
+	cqDir := t.TempDir()
+	sourceSpec := specs.Source{Name: "test", Path: "cloudquery/test", Version: "v1.4.5", Registry: specs.RegistryGithub, Tables: []string{"*"}}
+	syncTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	checkpoint, err := loadSyncCheckpoint(cqDir, sourceSpec)
+	require.NoError(t, err)
+	require.Nil(t, checkpoint)
+
+	checkpoint, err = newSyncCheckpoint(cqDir, sourceSpec, "uuid", syncTime)
+	require.NoError(t, err)
+	require.NoError(t, checkpoint.complete(schema.Tables{{Name: "table_1"}, {Name: "table_2"}}))
+
+	loaded, err := loadSyncCheckpoint(cqDir, sourceSpec)
+	require.NoError(t, err)
+	require.Equal(t, "uuid", loaded.InvocationUUID)
+	require.Equal(t, syncTime, loaded.SyncTime)
+	require.Equal(t, []string{"table_1", "table_2"}, loaded.CompletedTables)
+
+	// a checkpoint is only resumed with the same source spec
+	changedSpec := sourceSpec
+	changedSpec.Tables = []string{"table_1"}
+	loaded, err = loadSyncCheckpoint(cqDir, changedSpec)
+	require.NoError(t, err)
+	require.Nil(t, loaded)
+
+	require.NoError(t, checkpoint.remove())
+	loaded, err = loadSyncCheckpoint(cqDir, sourceSpec)
+	require.NoError(t, err)
+	require.Nil(t, loaded)
+}
+
+func TestCheckpointBatches(t *testing.T) {
+	tables := schema.Tables{{Name: "table_1"}, {Name: "table_2"}, {Name: "table_3"}, {Name: "table_4"}}
+	checkpoint := &syncCheckpoint{CompletedTables: []string{"table_2"}}
+
+	batches := checkpointBatches(tables, checkpoint, 2)
+	require.Len(t, batches, 2)
+	require.Equal(t, []string{"table_1", "table_3"}, batches[0].TableNames())
+	require.Equal(t, []string{"table_4"}, batches[1].TableNames())
+}
+
+func TestBatchSourceSpec(t *testing.T) {
+	sourceSpec := specs.Source{Name: "test", Tables: []string{"*"}, SkipTables: []string{"table_3"}}
+	batch := schema.Tables{{Name: "table_1", Relations: schema.Tables{{Name: "table_1_child"}}}}
+
+	batchSpec := batchSourceSpec(sourceSpec, batch)
+	require.Equal(t, []string{"table_1", "table_1_child"}, batchSpec.Tables)
+	require.Nil(t, batchSpec.SkipTables)
+	require.True(t, batchSpec.SkipDependentTables)
+	require.Equal(t, []string{"*"}, sourceSpec.Tables)
+}
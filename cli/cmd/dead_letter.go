@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudquery/filetypes"
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/google/uuid"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	onErrorFail       = "fail"
+	onErrorContinue   = "continue"
+	onErrorDeadLetter = "dead_letter"
+
+	defaultDeadLetterFormat = filetypes.FormatTypeJSON
+	// deadLetterBatchSize is the number of resources of a table buffered before they are written to a dead-letter file
+	deadLetterBatchSize = 10000
+	// defaultDeadLetterRecent is the number of resources of a table sent to a destination kept in case it fails, for
+	// destinations without a batch_size
+	defaultDeadLetterRecent = 10000
+)
+
+var onErrorPolicies = []string{onErrorFail, onErrorContinue, onErrorDeadLetter}
+
+// onErrorOptions holds the policy applied when writing to a destination fails. With the default fail policy a failed
+// destination stops the sync of the source. With continue the other destinations keep receiving resources, and with
+// dead_letter the resources the failed destination didn't accept are also written to files in deadLetterDir.
+// Policies are set by the on_error field of the destination specs, and policies given with --on-error override them.
+type onErrorOptions struct {
+	policies         map[string]string
+	specPolicies     map[string]string
+	deadLetterDir    string
+	deadLetterFormat filetypes.FormatType
+}
+
+func parseOnErrorOptions(policies map[string]string, deadLetterDir string, deadLetterFormat string) (onErrorOptions, error) {
+	for destination, policy := range policies {
+		if !isOnErrorPolicy(policy) {
+			return onErrorOptions{}, fmt.Errorf("invalid on-error policy %q for destination %s. Options are: %v", policy, destination, onErrorPolicies)
+		}
+	}
+	switch deadLetterFormat {
+	case filetypes.FormatTypeCSV, filetypes.FormatTypeJSON, filetypes.FormatTypeParquet:
+	default:
+		return onErrorOptions{}, fmt.Errorf("invalid dead-letter format %q. Options are: csv, json, parquet", deadLetterFormat)
+	}
+	return onErrorOptions{policies: policies, deadLetterDir: deadLetterDir, deadLetterFormat: filetypes.FormatType(deadLetterFormat)}, nil
+}
+
+func isOnErrorPolicy(policy string) bool {
+	for _, p := range onErrorPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDestinations returns an error if a policy is given for a destination that isn't in the specs
+func (o onErrorOptions) validateDestinations(specReader *specs.SpecReader) error {
+	for destination := range o.policies {
+		if specReader.GetDestinationByName(destination) == nil {
+			return fmt.Errorf("destination %s given in --on-error not found in spec(s)", destination)
+		}
+	}
+	return nil
+}
+
+func (o onErrorOptions) policy(destination string) string {
+	if policy, ok := o.policies[destination]; ok {
+		return policy
+	}
+	if policy, ok := o.specPolicies[destination]; ok {
+		return policy
+	}
+	return onErrorFail
+}
+
+// withSpecPolicies returns a copy of the options with the policies set by the destination specs
+func (o onErrorOptions) withSpecPolicies(specPolicies map[string]string) onErrorOptions {
+	o.specPolicies = specPolicies
+	return o
+}
+
+// deadLetterQueue tracks the resources a destination failed to accept. A destination can fail after it accepted
+// resources it didn't write yet: the managed writer of the plugins buffers up to batch_size resources per table. So the
+// last resources of every table sent to the destination are kept in memory, up to its batch size. Once it failed,
+// these and all the following resources are counted as failed and, with the dead_letter policy, written to the
+// dead-letter files.
+type deadLetterQueue struct {
+	size   int
+	recent map[string]*recentResources
+	// newWriter is nil with the continue policy, which only counts the failed resources
+	newWriter func() (*deadLetterWriter, error)
+	writer    *deadLetterWriter
+	// failedResources is the number of resources that may not have been written by the destination
+	failedResources uint64
+}
+
+// recentResources are the last resources of a table sent to a destination, oldest first starting at next once full.
+// Only count is kept if the resources aren't written to dead-letter files.
+type recentResources struct {
+	resources [][]byte
+	next      int
+	count     int
+}
+
+func newDeadLetterQueue(size int, newWriter func() (*deadLetterWriter, error)) *deadLetterQueue {
+	if size <= 0 {
+		size = defaultDeadLetterRecent
+	}
+	return &deadLetterQueue{size: size, recent: make(map[string]*recentResources), newWriter: newWriter}
+}
+
+// sent keeps the resource accepted by the destination, replacing the oldest one of its table once the table has
+// size resources
+func (q *deadLetterQueue) sent(resource []byte) {
+	table := resourceTableName(resource)
+	r := q.recent[table]
+	if r == nil {
+		r = &recentResources{}
+		q.recent[table] = r
+	}
+	if r.count < q.size {
+		r.count++
+		if q.newWriter != nil {
+			r.resources = append(r.resources, resource)
+		}
+		return
+	}
+	if q.newWriter != nil {
+		r.resources[r.next] = resource
+		r.next = (r.next + 1) % len(r.resources)
+	}
+}
+
+// failed counts the kept resources and then resource, if not nil, as failed, and writes them to the dead-letter files
+func (q *deadLetterQueue) failed(resource []byte) error {
+	if q.newWriter != nil && q.writer == nil {
+		w, err := q.newWriter()
+		if err != nil {
+			return err
+		}
+		q.writer = w
+	}
+	tables := maps.Keys(q.recent)
+	slices.Sort(tables)
+	for _, table := range tables {
+		r := q.recent[table]
+		q.failedResources += uint64(r.count)
+		for i := range r.resources {
+			if err := q.writer.write(r.resources[(r.next+i)%len(r.resources)]); err != nil {
+				return err
+			}
+		}
+	}
+	q.reset()
+	if resource == nil {
+		return nil
+	}
+	q.failedResources++
+	if q.writer == nil {
+		return nil
+	}
+	return q.writer.write(resource)
+}
+
+// reset drops the kept resources, once the destination wrote them
+func (q *deadLetterQueue) reset() {
+	q.recent = make(map[string]*recentResources)
+}
+
+// deadLetterWriter writes the resources a destination failed to accept to files in one of the filetypes formats,
+// one file per table and batch: <dir>/<table>.<format>.<uuid>
+type deadLetterWriter struct {
+	dir       string
+	format    filetypes.FormatType
+	client    *filetypes.Client
+	tables    map[string]*schema.Table
+	batches   map[string][][]any
+	resources uint64
+}
+
+func newDeadLetterWriter(dir string, format filetypes.FormatType, tables schema.Tables) (*deadLetterWriter, error) {
+	client, err := filetypes.NewClient(&filetypes.FileSpec{Format: format})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter %s writer: %w", format, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter directory %s: %w", dir, err)
+	}
+	flattened := tables.FlattenTables()
+	w := &deadLetterWriter{
+		dir:     dir,
+		format:  format,
+		client:  client,
+		tables:  make(map[string]*schema.Table, len(flattened)),
+		batches: make(map[string][][]any),
+	}
+	for _, table := range flattened {
+		w.tables[table.Name] = table
+	}
+	return w, nil
+}
+
+func (w *deadLetterWriter) write(resource []byte) error {
+	var r schema.DestinationResource
+	if err := json.Unmarshal(resource, &r); err != nil {
+		return fmt.Errorf("failed to unmarshal dead-letter resource: %w", err)
+	}
+	if _, ok := w.tables[r.TableName]; !ok {
+		return fmt.Errorf("unknown table %s of dead-letter resource", r.TableName)
+	}
+	w.batches[r.TableName] = append(w.batches[r.TableName], schema.TransformWithTransformer(w.client, r.Data))
+	w.resources++
+	if len(w.batches[r.TableName]) >= deadLetterBatchSize {
+		return w.flushTable(r.TableName)
+	}
+	return nil
+}
+
+func (w *deadLetterWriter) flushTable(table string) error {
+	batch := w.batches[table]
+	if len(batch) == 0 {
+		return nil
+	}
+	name := filepath.Join(w.dir, fmt.Sprintf("%s.%s.%s", table, w.format, uuid.NewString()))
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create dead-letter file %s: %w", name, err)
+	}
+	defer f.Close()
+	if err := w.client.WriteTableBatchFile(f, w.tables[table], batch); err != nil {
+		return fmt.Errorf("failed to write dead-letter file %s: %w", name, err)
+	}
+	delete(w.batches, table)
+	return f.Close()
+}
+
+// close writes the resources that are still buffered
+func (w *deadLetterWriter) close() error {
+	for table := range w.batches {
+		if err := w.flushTable(table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudquery/filetypes"
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOnErrorOptions(t *testing.T) {
+	opts, err := parseOnErrorOptions(map[string]string{"postgresql": onErrorContinue, "s3": onErrorDeadLetter}, "dead-letter", "json")
+	require.NoError(t, err)
+	require.Equal(t, onErrorContinue, opts.policy("postgresql"))
+	require.Equal(t, onErrorDeadLetter, opts.policy("s3"))
+	require.Equal(t, onErrorFail, opts.policy("bigquery"))
+	require.Equal(t, filetypes.FormatType(filetypes.FormatTypeJSON), opts.deadLetterFormat)
+
+	_, err = parseOnErrorOptions(map[string]string{"postgresql": "retry"}, "dead-letter", "json")
+	require.ErrorContains(t, err, `invalid on-error policy "retry"`)
+
+	_, err = parseOnErrorOptions(nil, "dead-letter", "xml")
+	require.ErrorContains(t, err, `invalid dead-letter format "xml"`)
+
+	// policies given with --on-error override the ones of the specs
+	opts = opts.withSpecPolicies(map[string]string{"postgresql": onErrorDeadLetter, "bigquery": onErrorContinue})
+	require.Equal(t, onErrorContinue, opts.policy("postgresql"))
+	require.Equal(t, onErrorContinue, opts.policy("bigquery"))
+}
+
+func TestDeadLetterQueue(t *testing.T) {
+	table := &schema.Table{Name: "test_table", Columns: schema.ColumnList{{Name: "name", Type: schema.TypeString}}}
+	other := &schema.Table{Name: "other_table", Columns: schema.ColumnList{{Name: "name", Type: schema.TypeString}}}
+	resource := func(table *schema.Table, name string) []byte {
+		text := &schema.Text{}
+		require.NoError(t, text.Set(name))
+		b, err := json.Marshal(schema.DestinationResource{TableName: table.Name, Data: schema.CQTypes{text}})
+		require.NoError(t, err)
+		return b
+	}
+
+	dir := filepath.Join(t.TempDir(), "dead-letter")
+	q := newDeadLetterQueue(2, func() (*deadLetterWriter, error) {
+		return newDeadLetterWriter(dir, filetypes.FormatTypeJSON, schema.Tables{table, other})
+	})
+	q.sent(resource(table, "written"))
+	q.reset()
+	// only the last 2 resources sent of every table are kept
+	for _, name := range []string{"dropped", "second", "third"} {
+		q.sent(resource(table, name))
+	}
+	q.sent(resource(other, "other"))
+	require.Nil(t, q.writer, "writer created before the destination failed")
+	require.NoError(t, q.failed(resource(table, "fourth")))
+	require.NoError(t, q.failed(nil))
+	require.NoError(t, q.writer.close())
+	require.Equal(t, uint64(4), q.failedResources)
+
+	files, err := filepath.Glob(filepath.Join(dir, "test_table.json.*"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	b, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Len(t, lines, 3)
+	for i, name := range []string{"second", "third", "fourth"} {
+		require.Contains(t, lines[i], `"`+name+`"`)
+	}
+	files, err = filepath.Glob(filepath.Join(dir, "other_table.json.*"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	// with the continue policy the resources are only counted
+	q = newDeadLetterQueue(2, nil)
+	for _, name := range []string{"first", "second", "third"} {
+		q.sent(resource(table, name))
+	}
+	require.NoError(t, q.failed(resource(table, "fourth")))
+	require.NoError(t, q.failed(resource(other, "other")))
+	require.Nil(t, q.writer)
+	require.Equal(t, uint64(4), q.failedResources)
+}
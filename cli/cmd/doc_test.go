@@ -11,6 +11,11 @@ var docFiles = []string{
 	"cloudquery_sync.md",
 	"cloudquery_migrate.md",
 	"cloudquery_tables.md",
+	"cloudquery_validate.md",
+	"cloudquery_plugin.md",
+	"cloudquery_plugin_install.md",
+	"cloudquery_plugin_list.md",
+	"cloudquery_plugin_prune.md",
 }
 
 func TestDoc(t *testing.T) {
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	destination "github.com/cloudquery/plugin-sdk/clients/destination/v0"
+	"github.com/cloudquery/plugin-sdk/clients/source/v1"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	metricsNamespace    = "cloudquery"
+	metricsPollInterval = 5 * time.Second
+)
+
+// syncMetrics holds the Prometheus metrics exposed on the /metrics endpoint during sync.
+// All methods are no-ops on a nil *syncMetrics, so callers don't need to check whether metrics are enabled.
+type syncMetrics struct {
+	registry *prometheus.Registry
+
+	tableResources     *prometheus.CounterVec
+	sourceResources    *prometheus.GaugeVec
+	sourceErrors       *prometheus.GaugeVec
+	sourcePanics       *prometheus.GaugeVec
+	resourcesReceived  *prometheus.CounterVec
+	resourcesSent      *prometheus.CounterVec
+	resourcesFailed    *prometheus.CounterVec
+	fanOutBacklog      *prometheus.GaugeVec
+	fanOutWait         *prometheus.CounterVec
+	destinationWrites  *prometheus.GaugeVec
+	destinationErrors  *prometheus.GaugeVec
+	syncRunning        *prometheus.GaugeVec
+	lastSyncSuccessful *prometheus.GaugeVec
+}
+
+func newSyncMetrics() *syncMetrics {
+	m := &syncMetrics{
+		registry: prometheus.NewRegistry(),
+		tableResources: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "table_resources_total",
+			Help: "Number of resources received by the CLI from the source plugin, per table.",
+		}, []string{"source", "table"}),
+		sourceResources: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "source_resources",
+			Help: "Number of resources synced, as reported by the source plugin.",
+		}, []string{"source"}),
+		sourceErrors: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "source_errors",
+			Help: "Number of errors, as reported by the source plugin.",
+		}, []string{"source"}),
+		sourcePanics: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "source_panics",
+			Help: "Number of panics, as reported by the source plugin.",
+		}, []string{"source"}),
+		resourcesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "resources_received_total",
+			Help: "Number of resources received by the CLI from the source plugin.",
+		}, []string{"source"}),
+		resourcesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "resources_sent_total",
+			Help: "Number of resources handed by the CLI to the destination plugin.",
+		}, []string{"source", "destination"}),
+		resourcesFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "resources_failed_total",
+			Help: "Number of resources not handed to the destination plugin because writing to it failed.",
+		}, []string{"source", "destination"}),
+		fanOutBacklog: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "fanout_backlog",
+			Help: "Number of resources received from the source that were not yet handed to the destination.",
+		}, []string{"source", "destination"}),
+		fanOutWait: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "fanout_wait_seconds_total",
+			Help: "Time spent waiting for the destination to accept resources. A fast growing value points to a slow destination.",
+		}, []string{"source", "destination"}),
+		destinationWrites: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "destination_writes",
+			Help: "Number of successful writes, as reported by the destination plugin.",
+		}, []string{"source", "destination"}),
+		destinationErrors: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "destination_errors",
+			Help: "Number of failed writes, as reported by the destination plugin.",
+		}, []string{"source", "destination"}),
+		syncRunning: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "running",
+			Help: "Whether the sync of the source is currently running (1) or not (0).",
+		}, []string{"source"}),
+		lastSyncSuccessful: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Subsystem: "sync", Name: "last_successful",
+			Help: "Whether the last sync of the source finished successfully (1) or not (0).",
+		}, []string{"source"}),
+	}
+	m.registry.MustRegister(
+		m.tableResources,
+		m.sourceResources,
+		m.sourceErrors,
+		m.sourcePanics,
+		m.resourcesReceived,
+		m.resourcesSent,
+		m.resourcesFailed,
+		m.fanOutBacklog,
+		m.fanOutWait,
+		m.destinationWrites,
+		m.destinationErrors,
+		m.syncRunning,
+		m.lastSyncSuccessful,
+	)
+	return m
+}
+
+// serve starts an HTTP server exposing the metrics on /metrics at the given address.
+// The returned function shuts the server down.
+func (m *syncMetrics) serve(address string) (func(), error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on metrics address %s: %w", address, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("Metrics server failed")
+		}
+	}()
+	log.Info().Str("address", listener.Addr().String()).Msg("Serving metrics")
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to shut down metrics server")
+		}
+	}, nil
+}
+
+func (m *syncMetrics) syncStarted(sourceSpec specs.Source) {
+	if m == nil {
+		return
+	}
+	m.syncRunning.WithLabelValues(sourceSpec.Name).Set(1)
+}
+
+func (m *syncMetrics) syncFinished(sourceSpec specs.Source, success bool) {
+	if m == nil {
+		return
+	}
+	m.syncRunning.WithLabelValues(sourceSpec.Name).Set(0)
+	if success {
+		m.lastSyncSuccessful.WithLabelValues(sourceSpec.Name).Set(1)
+	} else {
+		m.lastSyncSuccessful.WithLabelValues(sourceSpec.Name).Set(0)
+	}
+}
+
+func (m *syncMetrics) resourceReceived(sourceSpec specs.Source, destinationsSpecs []specs.Destination, resource []byte) {
+	if m == nil {
+		return
+	}
+	m.resourcesReceived.WithLabelValues(sourceSpec.Name).Inc()
+	m.tableResources.WithLabelValues(sourceSpec.Name, resourceTableName(resource)).Inc()
+	for _, destinationSpec := range destinationsSpecs {
+		m.fanOutBacklog.WithLabelValues(sourceSpec.Name, destinationSpec.Name).Inc()
+	}
+}
+
+func (m *syncMetrics) resourceSent(sourceSpec specs.Source, destinationSpec specs.Destination, wait time.Duration) {
+	if m == nil {
+		return
+	}
+	m.resourcesSent.WithLabelValues(sourceSpec.Name, destinationSpec.Name).Inc()
+	m.fanOutBacklog.WithLabelValues(sourceSpec.Name, destinationSpec.Name).Dec()
+	m.fanOutWait.WithLabelValues(sourceSpec.Name, destinationSpec.Name).Add(wait.Seconds())
+}
+
+func (m *syncMetrics) resourceFailed(sourceSpec specs.Source, destinationSpec specs.Destination) {
+	if m == nil {
+		return
+	}
+	m.resourcesFailed.WithLabelValues(sourceSpec.Name, destinationSpec.Name).Inc()
+	m.fanOutBacklog.WithLabelValues(sourceSpec.Name, destinationSpec.Name).Dec()
+}
+
+// poll updates the metrics reported by the source and destination plugins every metricsPollInterval,
+// until the context is done.
+func (m *syncMetrics) poll(ctx context.Context, sourceSpec specs.Source, sourceClient *source.Client, destinationsSpecs []specs.Destination, destClients []*destination.Client) {
+	if m == nil {
+		return
+	}
+	t := time.NewTicker(metricsPollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.update(ctx, sourceSpec, sourceClient, destinationsSpecs, destClients)
+		}
+	}
+}
+
+func (m *syncMetrics) update(ctx context.Context, sourceSpec specs.Source, sourceClient *source.Client, destinationsSpecs []specs.Destination, destClients []*destination.Client) {
+	if m == nil {
+		return
+	}
+	metrics, err := sourceClient.GetMetrics(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("source", sourceSpec.Name).Msg("Failed to get source metrics")
+	} else {
+		// source plugins aggregate their metrics before sending them, so only totals are available
+		m.sourceResources.WithLabelValues(sourceSpec.Name).Set(float64(metrics.TotalResources()))
+		m.sourceErrors.WithLabelValues(sourceSpec.Name).Set(float64(metrics.TotalErrors()))
+		m.sourcePanics.WithLabelValues(sourceSpec.Name).Set(float64(metrics.TotalPanics()))
+	}
+	for i, destinationSpec := range destinationsSpecs {
+		destMetrics, err := destClients[i].GetMetrics(ctx)
+		if err != nil {
+			log.Warn().Err(err).Str("source", sourceSpec.Name).Str("destination", destinationSpec.Name).Msg("Failed to get destination metrics")
+			continue
+		}
+		m.destinationWrites.WithLabelValues(sourceSpec.Name, destinationSpec.Name).Set(float64(destMetrics.Writes))
+		m.destinationErrors.WithLabelValues(sourceSpec.Name, destinationSpec.Name).Set(float64(destMetrics.Errors))
+	}
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncMetrics(t *testing.T) {
+	sourceSpec := specs.Source{Name: "test"}
+	destinationsSpecs := []specs.Destination{{Name: "test-1"}, {Name: "test-2"}}
+
+	m := newSyncMetrics()
+	m.syncStarted(sourceSpec)
+	require.Equal(t, float64(1), testutil.ToFloat64(m.syncRunning.WithLabelValues("test")))
+
+	m.resourceReceived(sourceSpec, destinationsSpecs, []byte(`{"table_name":"test_table","data":[]}`))
+	m.resourceReceived(sourceSpec, destinationsSpecs, []byte(`{"table_name":"test_table","data":[]}`))
+	m.resourceSent(sourceSpec, destinationsSpecs[0], time.Second)
+	require.Equal(t, float64(2), testutil.ToFloat64(m.resourcesReceived.WithLabelValues("test")))
+	require.Equal(t, float64(2), testutil.ToFloat64(m.tableResources.WithLabelValues("test", "test_table")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.resourcesSent.WithLabelValues("test", "test-1")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.fanOutBacklog.WithLabelValues("test", "test-1")))
+	require.Equal(t, float64(2), testutil.ToFloat64(m.fanOutBacklog.WithLabelValues("test", "test-2")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.fanOutWait.WithLabelValues("test", "test-1")))
+
+	m.syncFinished(sourceSpec, true)
+	require.Equal(t, float64(0), testutil.ToFloat64(m.syncRunning.WithLabelValues("test")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.lastSyncSuccessful.WithLabelValues("test")))
+}
+
+func TestSyncMetricsNil(t *testing.T) {
+	var m *syncMetrics
+	sourceSpec := specs.Source{Name: "test"}
+	destinationsSpecs := []specs.Destination{{Name: "test-1"}}
+	require.NotPanics(t, func() {
+		m.syncStarted(sourceSpec)
+		m.resourceReceived(sourceSpec, destinationsSpecs, nil)
+		m.resourceSent(sourceSpec, destinationsSpecs[0], time.Second)
+		m.syncFinished(sourceSpec, false)
+	})
+}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/cloudquery/plugin-sdk/clients/discovery/v0"
@@ -18,6 +20,10 @@ const (
 cloudquery migrate ./directory
 # Run migration for plugins specified in directory and config files
 cloudquery migrate ./directory ./aws.yml ./pg.yml
+# Print the migration plan of every destination without applying it
+cloudquery migrate ./directory --dry-run
+# Print the migration plan and write it to a file as JSON for review
+cloudquery migrate ./directory --dry-run --plan-file plan.json
 `
 )
 
@@ -30,6 +36,8 @@ func NewCmdMigrate() *cobra.Command {
 		Args:    cobra.MinimumNArgs(1),
 		RunE:    migrate,
 	}
+	cmd.Flags().Bool("dry-run", false, "Only print the migration plan of every destination (tables to create, columns to add, remove or change, and whether 'migrate_mode: forced' is needed) without applying it. Supported by the PostgreSQL destination from v3.1.0, when not run with 'registry: grpc'. Fails without migrating anything if another destination is used.")
+	cmd.Flags().String("plan-file", "", "Write the migration plan of --dry-run to this file as JSON")
 	return cmd
 }
 
@@ -39,14 +47,36 @@ func migrate(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+	planFile, err := cmd.Flags().GetString("plan-file")
+	if err != nil {
+		return err
+	}
+	if planFile != "" && !dryRun {
+		return fmt.Errorf("--plan-file can only be used with --dry-run")
+	}
+
 	ctx := cmd.Context()
 	log.Info().Strs("args", args).Msg("Loading spec(s)")
 	fmt.Printf("Loading spec(s) from %s\n", strings.Join(args, ", "))
-	specReader, err := specs.NewSpecReader(args)
+	specReader, _, err := readSpecs(args)
 	if err != nil {
 		return fmt.Errorf("failed to load spec(s) from %s. Error: %w", strings.Join(args, ", "), err)
 	}
 
+	var planDir string
+	var plans []destinationMigrationPlan
+	if dryRun {
+		if planDir, err = os.MkdirTemp("", "cloudquery-migrate-plan-"); err != nil {
+			return fmt.Errorf("failed to create migration plan directory: %w", err)
+		}
+		defer os.RemoveAll(planDir)
+		defer os.Unsetenv(migratePlanDirEnv)
+	}
+
 	for _, sourceSpec := range specReader.Sources {
 		if len(sourceSpec.Destinations) == 0 {
 			return fmt.Errorf("no destinations found for source %s", sourceSpec.Name)
@@ -57,7 +87,21 @@ func migrate(cmd *cobra.Command, args []string) error {
 			if spec == nil {
 				return fmt.Errorf("failed to find destination %s in source %s", destination, sourceSpec.Name)
 			}
-			destinationsSpecs = append(destinationsSpecs, *spec)
+			destinationSpec := *spec
+			if dryRun && destinationSpec.Registry == specs.RegistryGrpc {
+				return fmt.Errorf("destination %s doesn't support --dry-run: plugins with 'registry: grpc' can't write a migration plan for the CLI", destinationSpec.Name)
+			}
+			destinationsSpecs = append(destinationsSpecs, destinationSpec)
+		}
+		if dryRun {
+			// the destination plugins are started by the CLI, and inherit the variable
+			sourcePlanDir := filepath.Join(planDir, sourceSpec.Name)
+			if err := os.MkdirAll(sourcePlanDir, 0755); err != nil {
+				return fmt.Errorf("failed to create migration plan directory: %w", err)
+			}
+			if err := os.Setenv(migratePlanDirEnv, sourcePlanDir); err != nil {
+				return fmt.Errorf("failed to set %s: %w", migratePlanDirEnv, err)
+			}
 		}
 		discoveryClient, err := discovery.NewClient(ctx, sourceSpec.Registry, registry.PluginTypeSource, sourceSpec.Path, sourceSpec.Version)
 		if err != nil {
@@ -69,9 +113,12 @@ func migrate(cmd *cobra.Command, args []string) error {
 				log.Error().Err(discoveryErr).Msg("failed to terminate discovery client")
 				fmt.Println("failed to terminate discovery client:", discoveryErr)
 			}
-			if err := migrateConnectionV0(ctx, cqDir, *sourceSpec, destinationsSpecs); err != nil {
+			if err := migrateConnectionV0(ctx, cqDir, *sourceSpec, destinationsSpecs, dryRun); err != nil {
 				return fmt.Errorf("failed to migrate source %s: %w", sourceSpec.Name, err)
 			}
+			if plans, err = appendMigrationPlans(plans, planDir, *sourceSpec); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -80,21 +127,52 @@ func migrate(cmd *cobra.Command, args []string) error {
 		}
 
 		if slices.Index(versions, "v1") != -1 {
-			if err := migrateConnectionV1(ctx, cqDir, *sourceSpec, destinationsSpecs); err != nil {
+			if err := migrateConnectionV1(ctx, cqDir, *sourceSpec, destinationsSpecs, dryRun); err != nil {
 				return fmt.Errorf("failed to migrate source %s: %w", sourceSpec.Name, err)
 			}
+			if plans, err = appendMigrationPlans(plans, planDir, *sourceSpec); err != nil {
+				return err
+			}
 			continue
 		}
 
 		if slices.Index(versions, "v0") != -1 {
-			if err := migrateConnectionV0(ctx, cqDir, *sourceSpec, destinationsSpecs); err != nil {
+			if err := migrateConnectionV0(ctx, cqDir, *sourceSpec, destinationsSpecs, dryRun); err != nil {
 				return fmt.Errorf("failed to migrate source %s: %w", sourceSpec.Name, err)
 			}
+			if plans, err = appendMigrationPlans(plans, planDir, *sourceSpec); err != nil {
+				return err
+			}
 			continue
 		}
 
 		return fmt.Errorf("failed to migrate source %s, unknown versions %v", sourceSpec.Name, versions)
 	}
 
+	if dryRun {
+		printMigrationPlans(os.Stdout, plans)
+		if planFile != "" {
+			if err := writeMigrationPlans(planFile, plans); err != nil {
+				return err
+			}
+			fmt.Printf("Migration plan written to %s\n", planFile)
+		}
+		fmt.Println("Dry run completed, no changes were applied.")
+	}
 	return nil
 }
+
+// appendMigrationPlans reads the plans written by the destinations of the source in a dry run
+func appendMigrationPlans(plans []destinationMigrationPlan, planDir string, sourceSpec specs.Source) ([]destinationMigrationPlan, error) {
+	if planDir == "" {
+		return plans, nil
+	}
+	for _, destination := range sourceSpec.Destinations {
+		tables, err := readMigrationPlan(migratePlanPath(planDir, sourceSpec.Name, destination))
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, destinationMigrationPlan{Source: sourceSpec.Name, Destination: destination, Tables: tables})
+	}
+	return plans, nil
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudquery/plugin-sdk/specs"
+	"golang.org/x/mod/semver"
+)
+
+// migratePlanDirEnv is the variable the CLI sets in a dry run to the directory the destination plugins write their
+// migration plan to, in a file named after the destination
+const migratePlanDirEnv = "CQ_MIGRATE_PLAN_DIR"
+
+// migrateDryRunVersions are the destination plugins that write a migration plan instead of migrating when
+// migratePlanDirEnv is set, with the first version that does
+var migrateDryRunVersions = map[string]string{
+	"postgresql": "v3.1.0",
+}
+
+// tableMigrationPlan is the change a destination would apply to a single table, as written by the plugin
+type tableMigrationPlan struct {
+	Table          string   `json:"table"`
+	Action         string   `json:"action"`
+	AddColumns     []string `json:"add_columns,omitempty"`
+	RemoveColumns  []string `json:"remove_columns,omitempty"`
+	ChangeColumns  []string `json:"change_columns,omitempty"`
+	RequiresForced bool     `json:"requires_forced_migration"`
+}
+
+// destinationMigrationPlan is the migration plan of a destination for the tables of a source
+type destinationMigrationPlan struct {
+	Source      string               `json:"source"`
+	Destination string               `json:"destination"`
+	Tables      []tableMigrationPlan `json:"tables"`
+}
+
+func (p destinationMigrationPlan) requiresForced() bool {
+	for _, table := range p.Tables {
+		if table.RequiresForced {
+			return true
+		}
+	}
+	return false
+}
+
+// supportsMigrateDryRun returns true if the plugin writes a migration plan in a dry run. Development builds are
+// assumed to be up to date.
+func supportsMigrateDryRun(name string, version string) bool {
+	minVersion, ok := migrateDryRunVersions[name]
+	if !ok {
+		return false
+	}
+	if version == "Development" {
+		return true
+	}
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	return semver.IsValid(version) && semver.Compare(version, minVersion) >= 0
+}
+
+// checkMigrateDryRun fails if any of the destinations would apply the migration instead of writing its plan
+func checkMigrateDryRun(ctx context.Context, destClients destinationClients, destinationsSpecs []specs.Destination) error {
+	for i, destinationSpec := range destinationsSpecs {
+		name, err := destClients[i].Name(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get name of destination %s: %w", destinationSpec.Name, err)
+		}
+		version, err := destClients[i].Version(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get version of destination %s: %w", destinationSpec.Name, err)
+		}
+		if !supportsMigrateDryRun(name, version) {
+			return fmt.Errorf("destination %s (plugin %s %s) doesn't support --dry-run", destinationSpec.Name, name, version)
+		}
+	}
+	return nil
+}
+
+// migratePlanPath returns the file the destination writes its plan for the source to
+func migratePlanPath(dir string, source string, destination string) string {
+	return filepath.Join(dir, source, destination+".json")
+}
+
+// readMigrationPlan reads the plan written by a destination
+func readMigrationPlan(path string) ([]tableMigrationPlan, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no migration plan was written to %s by the destination", path)
+		}
+		return nil, fmt.Errorf("failed to read migration plan: %w", err)
+	}
+	var tables []tableMigrationPlan
+	if err := json.Unmarshal(b, &tables); err != nil {
+		return nil, fmt.Errorf("failed to decode migration plan %s: %w", path, err)
+	}
+	if tables == nil {
+		tables = []tableMigrationPlan{}
+	}
+	return tables, nil
+}
+
+// printMigrationPlans prints the plans grouped by destination and table. Tables without changes are left out.
+func printMigrationPlans(w io.Writer, plans []destinationMigrationPlan) {
+	for _, plan := range plans {
+		fmt.Fprintf(w, "Migration plan of destination %s for source %s:\n", plan.Destination, plan.Source)
+		changed := 0
+		for _, table := range plan.Tables {
+			if table.Action == "none" {
+				continue
+			}
+			changed++
+			fmt.Fprintf(w, "  %s: %s", table.Table, table.Action)
+			if table.RequiresForced {
+				fmt.Fprint(w, " (the table is dropped and created again, requires 'migrate_mode: forced')")
+			}
+			fmt.Fprintln(w)
+			printPlanColumns(w, "add columns", table.AddColumns)
+			printPlanColumns(w, "remove columns", table.RemoveColumns)
+			printPlanColumns(w, "change columns", table.ChangeColumns)
+		}
+		if changed == 0 {
+			fmt.Fprintln(w, "  No changes")
+			continue
+		}
+		if plan.requiresForced() {
+			fmt.Fprintln(w, "  'migrate_mode: forced' is required to apply this plan")
+		}
+	}
+}
+
+func printPlanColumns(w io.Writer, title string, columns []string) {
+	if len(columns) > 0 {
+		fmt.Fprintf(w, "    %s: %s\n", title, strings.Join(columns, ", "))
+	}
+}
+
+// writeMigrationPlans writes the plans to the file given by --plan-file as JSON
+func writeMigrationPlans(path string, plans []destinationMigrationPlan) error {
+	b, err := json.MarshalIndent(plans, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal migration plans: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("failed to write migration plans to %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadMigrationPlan(t *testing.T) {
+	dir := t.TempDir()
+	path := migratePlanPath(dir, "aws", "postgresql")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(`[{"table":"aws_ec2_instances","action":"alter","add_columns":["name"],"requires_forced_migration":false}]`), 0644))
+
+	tables, err := readMigrationPlan(path)
+	require.NoError(t, err)
+	require.Equal(t, []tableMigrationPlan{{Table: "aws_ec2_instances", Action: "alter", AddColumns: []string{"name"}}}, tables)
+
+	_, err = readMigrationPlan(filepath.Join(dir, "missing.json"))
+	require.Error(t, err)
+}
+
+func TestSupportsMigrateDryRun(t *testing.T) {
+	cases := []struct {
+		name    string
+		version string
+		want    bool
+	}{
+		{"postgresql", "v3.1.0", true},
+		{"postgresql", "3.2.1", true},
+		{"postgresql", "Development", true},
+		{"postgresql", "v3.0.3", false},
+		{"postgresql", "invalid", false},
+		{"sqlite", "v9.0.0", false},
+		{"sqlite", "Development", false},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.want, supportsMigrateDryRun(tc.name, tc.version), "%s %s", tc.name, tc.version)
+	}
+}
+
+func TestPrintMigrationPlans(t *testing.T) {
+	plans := []destinationMigrationPlan{
+		{
+			Source:      "aws",
+			Destination: "postgresql",
+			Tables: []tableMigrationPlan{
+				{Table: "aws_ec2_instances", Action: "alter", AddColumns: []string{"name"}, RemoveColumns: []string{"old"}},
+				{Table: "aws_s3_buckets", Action: "recreate", ChangeColumns: []string{"id"}, RequiresForced: true},
+				{Table: "aws_iam_users", Action: "none"},
+			},
+		},
+		{Source: "aws", Destination: "unchanged", Tables: []tableMigrationPlan{}},
+	}
+	var buf bytes.Buffer
+	printMigrationPlans(&buf, plans)
+	require.Equal(t, `Migration plan of destination postgresql for source aws:
+  aws_ec2_instances: alter
+    add columns: name
+    remove columns: old
+  aws_s3_buckets: recreate (the table is dropped and created again, requires 'migrate_mode: forced')
+    change columns: id
+  'migrate_mode: forced' is required to apply this plan
+Migration plan of destination unchanged for source aws:
+  No changes
+`, buf.String())
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-func migrateConnectionV0(ctx context.Context, cqDir string, sourceSpec specs.Source, destinationsSpecs []specs.Destination) error {
+func migrateConnectionV0(ctx context.Context, cqDir string, sourceSpec specs.Source, destinationsSpecs []specs.Destination, dryRun bool) error {
 	destinationNames := make([]string, len(destinationsSpecs))
 	for i := range destinationsSpecs {
 		destinationNames[i] = destinationsSpecs[i].Name
@@ -39,6 +39,11 @@ func migrateConnectionV0(ctx context.Context, cqDir string, sourceSpec specs.Sou
 		return err
 	}
 	defer destClients.Close()
+	if dryRun {
+		if err := checkMigrateDryRun(ctx, destClients, destinationsSpecs); err != nil {
+			return err
+		}
+	}
 
 	selectedTables, tablesForSpecSupported, err := getTablesForSpec(ctx, sourceClient, sourceSpec)
 	if err != nil {
@@ -10,7 +10,7 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-func migrateConnectionV1(ctx context.Context, cqDir string, sourceSpec specs.Source, destinationsSpecs []specs.Destination) error {
+func migrateConnectionV1(ctx context.Context, cqDir string, sourceSpec specs.Source, destinationsSpecs []specs.Destination, dryRun bool) error {
 	destinationNames := make([]string, len(destinationsSpecs))
 	for i := range destinationsSpecs {
 		destinationNames[i] = destinationsSpecs[i].Name
@@ -39,6 +39,11 @@ func migrateConnectionV1(ctx context.Context, cqDir string, sourceSpec specs.Sou
 		return err
 	}
 	defer destClients.Close()
+	if dryRun {
+		if err := checkMigrateDryRun(ctx, destClients, destinationsSpecs); err != nil {
+			return err
+		}
+	}
 
 	if err := sourceClient.Init(ctx, sourceSpec); err != nil {
 		return fmt.Errorf("failed to init source %s: %w", sourceSpec.Name, err)
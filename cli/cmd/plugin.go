@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudquery/plugin-sdk/registry"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/spf13/cobra"
+)
+
+const (
+	pluginShort = "Manage the plugins cached in the cq-dir directory"
+	// pluginsDir is the directory under cq-dir where plugins are downloaded to, as plugins/<type>/<org>/<name>/<version>
+	pluginsDir = "plugins"
+)
+
+func NewCmdPlugin() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugin",
+		Short: pluginShort,
+		Long:  pluginShort,
+	}
+	cmd.AddCommand(
+		newCmdPluginInstall(),
+		newCmdPluginList(),
+		newCmdPluginPrune(),
+	)
+	return cmd
+}
+
+// cachedPlugin is a plugin version downloaded to the cq-dir directory
+type cachedPlugin struct {
+	Type    registry.PluginType `json:"type"`
+	Path    string              `json:"path"`
+	Version string              `json:"version"`
+	Dir     string              `json:"dir"`
+	Size    int64               `json:"size"`
+}
+
+func (p cachedPlugin) key() string {
+	return pluginKey(p.Type, p.Path, p.Version)
+}
+
+func pluginKey(typ registry.PluginType, path string, version string) string {
+	return fmt.Sprintf("%s/%s@%s", typ, path, version)
+}
+
+// specPlugin is a plugin referenced by a source or destination spec
+type specPlugin struct {
+	Type     registry.PluginType
+	Name     string
+	Registry specs.Registry
+	Path     string
+	Version  string
+}
+
+func (p specPlugin) key() string {
+	return pluginKey(p.Type, p.Path, p.Version)
+}
+
+// specPluginList is a list of plugins without duplicates
+type specPluginList struct {
+	seen    map[string]bool
+	plugins []specPlugin
+}
+
+func (l *specPluginList) add(p specPlugin) {
+	if l.seen == nil {
+		l.seen = make(map[string]bool)
+	}
+	if l.seen[p.key()] {
+		return
+	}
+	l.seen[p.key()] = true
+	l.plugins = append(l.plugins, p)
+}
+
+func (l *specPluginList) addSource(sourceSpec *specs.Source) {
+	l.add(specPlugin{Type: registry.PluginTypeSource, Name: sourceSpec.Name, Registry: sourceSpec.Registry, Path: sourceSpec.Path, Version: sourceSpec.Version})
+}
+
+func (l *specPluginList) addDestination(destinationSpec *specs.Destination) {
+	l.add(specPlugin{Type: registry.PluginTypeDestination, Name: destinationSpec.Name, Registry: destinationSpec.Registry, Path: destinationSpec.Path, Version: destinationSpec.Version})
+}
+
+// specPlugins returns the plugins referenced by the specs, without duplicates
+func specPlugins(specReader *specs.SpecReader) []specPlugin {
+	var list specPluginList
+	for _, sourceSpec := range specReader.Sources {
+		list.addSource(sourceSpec)
+	}
+	for _, destinationSpec := range specReader.Destinations {
+		list.addDestination(destinationSpec)
+	}
+	return list.plugins
+}
+
+// syncPlugins returns the plugins used to sync the given sources and their destinations, without duplicates
+func syncPlugins(specReader *specs.SpecReader, sources []*specs.Source) []specPlugin {
+	var list specPluginList
+	for _, sourceSpec := range sources {
+		list.addSource(sourceSpec)
+		for _, destination := range sourceSpec.Destinations {
+			if destinationSpec := specReader.GetDestinationByName(destination); destinationSpec != nil {
+				list.addDestination(destinationSpec)
+			}
+		}
+	}
+	return list.plugins
+}
+
+// githubPluginPath returns the path the plugin binary is downloaded to, matching the layout used by the plugin clients
+func githubPluginPath(cqDir string, typ registry.PluginType, path string, version string) (string, error) {
+	pathSplit := strings.Split(path, "/")
+	if len(pathSplit) != 2 {
+		return "", fmt.Errorf("invalid github plugin path: %s. format should be owner/repo", path)
+	}
+	localPath := filepath.Join(cqDir, pluginsDir, string(typ), pathSplit[0], pathSplit[1], version, "plugin")
+	return registry.WithBinarySuffix(localPath), nil
+}
+
+// listCachedPlugins returns the plugin versions downloaded to the cq-dir directory, sorted by type, path and version
+func listCachedPlugins(cqDir string) ([]cachedPlugin, error) {
+	var plugins []cachedPlugin
+	for _, typ := range []registry.PluginType{registry.PluginTypeSource, registry.PluginTypeDestination} {
+		// versions are 3 levels below the type directory: <org>/<name>/<version>
+		versionDirs, err := filepath.Glob(filepath.Join(cqDir, pluginsDir, string(typ), "*", "*", "*"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s plugins: %w", typ, err)
+		}
+		for _, dir := range versionDirs {
+			info, err := os.Stat(dir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat %s: %w", dir, err)
+			}
+			if !info.IsDir() {
+				continue
+			}
+			size, err := dirSize(dir)
+			if err != nil {
+				return nil, err
+			}
+			versionDir := filepath.Base(dir)
+			nameDir := filepath.Dir(dir)
+			orgDir := filepath.Dir(nameDir)
+			plugins = append(plugins, cachedPlugin{
+				Type:    typ,
+				Path:    filepath.Base(orgDir) + "/" + filepath.Base(nameDir),
+				Version: versionDir,
+				Dir:     dir,
+				Size:    size,
+			})
+		}
+	}
+	sort.SliceStable(plugins, func(i, j int) bool {
+		if plugins[i].Type != plugins[j].Type {
+			return plugins[i].Type > plugins[j].Type
+		}
+		if plugins[i].Path != plugins[j].Path {
+			return plugins[i].Path < plugins[j].Path
+		}
+		return plugins[i].Version < plugins[j].Version
+	})
+	return plugins, nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get size of %s: %w", dir, err)
+	}
+	return size, nil
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudquery/plugin-sdk/registry"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+const (
+	pluginInstallShort   = "Download all plugins referenced by the spec(s) to the cq-dir directory"
+	pluginInstallExample = `# Download the plugins used by the specs in a directory, for example while building a Docker image
+cloudquery plugin install ./directory
+# Download the plugins to a custom directory
+cloudquery plugin install ./directory --cq-dir /opt/cloudquery
+`
+)
+
+func newCmdPluginInstall() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "install [files or directories]",
+		Short:   pluginInstallShort,
+		Long:    pluginInstallShort,
+		Example: pluginInstallExample,
+		Args:    cobra.MinimumNArgs(1),
+		RunE:    pluginInstall,
+	}
+	return cmd
+}
+
+func pluginInstall(cmd *cobra.Command, args []string) error {
+	cqDir, err := cmd.Flags().GetString("cq-dir")
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+	log.Info().Strs("args", args).Msg("Loading spec(s)")
+	fmt.Printf("Loading spec(s) from %s\n", strings.Join(args, ", "))
+	specReader, _, err := readSpecs(args)
+	if err != nil {
+		return fmt.Errorf("failed to load spec(s) from %s. Error: %w", strings.Join(args, ", "), err)
+	}
+
+	for _, p := range specPlugins(specReader) {
+		downloaded, err := downloadPlugin(ctx, cqDir, p)
+		if err != nil {
+			return fmt.Errorf("failed to install %s plugin %s: %w", p.Type, p.Name, err)
+		}
+		if !downloaded {
+			fmt.Printf("Skipping %s plugin %s: %s plugins are not downloaded\n", p.Type, p.Name, p.Registry.String())
+			continue
+		}
+		fmt.Printf("Installed %s plugin %s (%s@%s)\n", p.Type, p.Name, p.Path, p.Version)
+	}
+	return nil
+}
+
+// downloadPlugin downloads the plugin to the cq-dir directory, unless it was downloaded before. Only plugins from the
+// GitHub registry are downloaded, for the others false is returned.
+func downloadPlugin(ctx context.Context, cqDir string, p specPlugin) (bool, error) {
+	if p.Registry != specs.RegistryGithub {
+		log.Info().Str("plugin", p.key()).Str("registry", p.Registry.String()).Msg("Skipping plugin that is not downloaded")
+		return false, nil
+	}
+	localPath, err := githubPluginPath(cqDir, p.Type, p.Path, p.Version)
+	if err != nil {
+		return false, err
+	}
+	org, name, _ := strings.Cut(p.Path, "/")
+	log.Info().Str("plugin", p.key()).Str("local_path", localPath).Msg("Installing plugin")
+	if err := registry.DownloadPluginFromGithub(ctx, localPath, org, name, p.Version, p.Type); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// downloadSyncPlugins downloads the plugins used to sync the sources before syncing them concurrently. The plugin
+// clients download missing plugins themselves, but concurrent downloads of the same plugin overwrite each other's
+// files, so every plugin is downloaded once up front.
+func downloadSyncPlugins(ctx context.Context, cqDir string, specReader *specs.SpecReader, sources []*specs.Source) error {
+	for _, p := range syncPlugins(specReader, sources) {
+		if _, err := downloadPlugin(ctx, cqDir, p); err != nil {
+			return fmt.Errorf("failed to download %s plugin %s: %w", p.Type, p.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/cloudquery/cloudquery/cli/internal/enum"
+	"github.com/spf13/cobra"
+)
+
+const (
+	pluginListShort   = "List the plugin versions cached in the cq-dir directory"
+	pluginListExample = `# List the cached plugins with their sizes
+cloudquery plugin list
+# List the cached plugins as JSON
+cloudquery plugin list --format json
+`
+)
+
+func newCmdPluginList() *cobra.Command {
+	format := enum.NewEnum([]string{"text", "json"}, "text")
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   pluginListShort,
+		Long:    pluginListShort,
+		Example: pluginListExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pluginList(cmd, format.String())
+		},
+	}
+	cmd.Flags().Var(format, "format", "Output format. One of: text, json")
+	return cmd
+}
+
+func pluginList(cmd *cobra.Command, format string) error {
+	cqDir, err := cmd.Flags().GetString("cq-dir")
+	if err != nil {
+		return err
+	}
+	plugins, err := listCachedPlugins(cqDir)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		if plugins == nil {
+			plugins = []cachedPlugin{}
+		}
+		b, err := json.MarshalIndent(plugins, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal plugins: %w", err)
+		}
+		fmt.Fprintln(os.Stdout, string(b))
+	default:
+		if len(plugins) == 0 {
+			fmt.Printf("No plugins found in %s\n", cqDir)
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TYPE\tPATH\tVERSION\tSIZE")
+		var total int64
+		for _, p := range plugins {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Type, p.Path, p.Version, formatBytes(p.Size))
+			total += p.Size
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("Total: %d plugin versions, %s\n", len(plugins), formatBytes(total))
+	}
+	return nil
+}
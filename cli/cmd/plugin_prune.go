@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+const (
+	pluginPruneShort   = "Delete the cached plugin versions that are not referenced by the spec(s)"
+	pluginPruneExample = `# Delete all cached plugin versions that are not used by the specs in a directory
+cloudquery plugin prune ./directory
+# Show what would be deleted, without deleting anything
+cloudquery plugin prune ./directory --dry-run
+`
+)
+
+func newCmdPluginPrune() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "prune [files or directories]",
+		Short:   pluginPruneShort,
+		Long:    pluginPruneShort,
+		Example: pluginPruneExample,
+		Args:    cobra.MinimumNArgs(1),
+		RunE:    pluginPrune,
+	}
+	cmd.Flags().Bool("dry-run", false, "Only print the plugin versions that would be deleted")
+	return cmd
+}
+
+func pluginPrune(cmd *cobra.Command, args []string) error {
+	cqDir, err := cmd.Flags().GetString("cq-dir")
+	if err != nil {
+		return err
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+	log.Info().Strs("args", args).Msg("Loading spec(s)")
+	fmt.Printf("Loading spec(s) from %s\n", strings.Join(args, ", "))
+	specReader, _, err := readSpecs(args)
+	if err != nil {
+		return fmt.Errorf("failed to load spec(s) from %s. Error: %w", strings.Join(args, ", "), err)
+	}
+
+	unused, err := unusedPlugins(cqDir, specReader)
+	if err != nil {
+		return err
+	}
+	if len(unused) == 0 {
+		fmt.Println("No unused plugins to prune")
+		return nil
+	}
+
+	var freed int64
+	for _, p := range unused {
+		if dryRun {
+			fmt.Printf("Would delete %s plugin %s@%s (%s)\n", p.Type, p.Path, p.Version, formatBytes(p.Size))
+			continue
+		}
+		log.Info().Str("plugin", p.key()).Str("dir", p.Dir).Msg("Deleting unused plugin")
+		if err := os.RemoveAll(p.Dir); err != nil {
+			return fmt.Errorf("failed to delete %s plugin %s@%s: %w", p.Type, p.Path, p.Version, err)
+		}
+		fmt.Printf("Deleted %s plugin %s@%s (%s)\n", p.Type, p.Path, p.Version, formatBytes(p.Size))
+		freed += p.Size
+	}
+	if !dryRun {
+		fmt.Printf("Pruned %d plugin versions, freed %s\n", len(unused), formatBytes(freed))
+	}
+	return nil
+}
+
+// unusedPlugins returns the cached plugin versions that none of the specs reference
+func unusedPlugins(cqDir string, specReader *specs.SpecReader) ([]cachedPlugin, error) {
+	cached, err := listCachedPlugins(cqDir)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	for _, p := range specPlugins(specReader) {
+		if p.Registry == specs.RegistryGithub {
+			used[p.key()] = true
+		}
+	}
+	var unused []cachedPlugin
+	for _, p := range cached {
+		if !used[p.key()] {
+			unused = append(unused, p)
+		}
+	}
+	return unused, nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/cloudquery/plugin-sdk/registry"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCachedPlugin(t *testing.T, cqDir string, typ registry.PluginType, pluginPath string, version string, size int) {
+	t.Helper()
+	localPath, err := githubPluginPath(cqDir, typ, pluginPath, version)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(localPath), 0755))
+	require.NoError(t, os.WriteFile(localPath, make([]byte, size), 0744))
+}
+
+func TestListCachedPlugins(t *testing.T) {
+	cqDir := t.TempDir()
+	writeCachedPlugin(t, cqDir, registry.PluginTypeDestination, "cloudquery/test", "v1.3.26", 20)
+	writeCachedPlugin(t, cqDir, registry.PluginTypeSource, "cloudquery/test", "v1.4.5", 10)
+	writeCachedPlugin(t, cqDir, registry.PluginTypeSource, "cloudquery/test", "v1.4.4", 30)
+
+	plugins, err := listCachedPlugins(cqDir)
+	require.NoError(t, err)
+	require.Len(t, plugins, 3)
+	require.Equal(t, "source/cloudquery/test@v1.4.4", plugins[0].key())
+	require.Equal(t, int64(30), plugins[0].Size)
+	require.Equal(t, "source/cloudquery/test@v1.4.5", plugins[1].key())
+	require.Equal(t, "destination/cloudquery/test@v1.3.26", plugins[2].key())
+
+	plugins, err = listCachedPlugins(t.TempDir())
+	require.NoError(t, err)
+	require.Empty(t, plugins)
+}
+
+func TestPluginPrune(t *testing.T) {
+	_, filename, _, _ := runtime.Caller(0)
+	testConfig := path.Join(path.Dir(filename), "testdata", "sync-success.yml")
+	cqDir := t.TempDir()
+	writeCachedPlugin(t, cqDir, registry.PluginTypeSource, "cloudquery/test", "v1.4.5", 10)
+	writeCachedPlugin(t, cqDir, registry.PluginTypeSource, "cloudquery/test", "v1.4.4", 10)
+	writeCachedPlugin(t, cqDir, registry.PluginTypeDestination, "cloudquery/test", "v1.3.26", 10)
+	writeCachedPlugin(t, cqDir, registry.PluginTypeDestination, "cloudquery/postgresql", "v2.0.0", 10)
+
+	defer CloseLogFile()
+	cmd := NewCmdRoot()
+	cmd.SetArgs([]string{"plugin", "prune", testConfig, "--cq-dir", cqDir, "--log-file-name", path.Join(cqDir, "cloudquery.log")})
+	require.NoError(t, cmd.Execute())
+
+	plugins, err := listCachedPlugins(cqDir)
+	require.NoError(t, err)
+	require.Len(t, plugins, 2)
+	require.Equal(t, "source/cloudquery/test@v1.4.5", plugins[0].key())
+	require.Equal(t, "destination/cloudquery/test@v1.3.26", plugins[1].key())
+}
+
+func TestFormatBytes(t *testing.T) {
+	require.Equal(t, "512 B", formatBytes(512))
+	require.Equal(t, "1.5 KiB", formatBytes(1536))
+	require.Equal(t, "20.0 MiB", formatBytes(20*1024*1024))
+}
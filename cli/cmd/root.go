@@ -128,6 +128,8 @@ func NewCmdRoot() *cobra.Command {
 		NewCmdMigrate(),
 		newCmdDoc(),
 		NewCmdTables(),
+		NewCmdValidate(),
+		NewCmdPlugin(),
 	)
 	cmd.CompletionOptions.HiddenDefaultCmd = true
 	cmd.DisableAutoGenTag = true
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudquery/plugin-sdk/specs"
+	"gopkg.in/yaml.v3"
+)
+
+// onErrorSpecField is the field of destination specs that sets their on-error policy. The SDK doesn't know about it,
+// so the CLI removes it from the specs before they are read.
+const onErrorSpecField = "on_error"
+
+// readSpecs loads the specs from the given paths the same way specs.NewSpecReader does, and returns the on-error
+// policies set by the destination specs, by destination name.
+func readSpecs(paths []string) (*specs.SpecReader, map[string]string, error) {
+	policies := make(map[string]string)
+	files, err := specFiles(paths)
+	if err != nil {
+		// the spec reader reports missing files the usual way
+		specReader, err := specs.NewSpecReader(paths)
+		return specReader, policies, err
+	}
+
+	stripped := make(map[string][]byte)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read file %s: %w", file, err)
+		}
+		content, filePolicies, err := stripOnErrorPolicies(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read on_error of file %s: %w", file, err)
+		}
+		if len(filePolicies) == 0 {
+			continue
+		}
+		for destination, policy := range filePolicies {
+			policies[destination] = policy
+		}
+		stripped[file] = content
+	}
+	if len(stripped) == 0 {
+		specReader, err := specs.NewSpecReader(paths)
+		return specReader, policies, err
+	}
+
+	// the files are read in the same order as the spec reader reads the given paths, with the files that set on_error
+	// replaced by copies without it
+	dir, err := os.MkdirTemp("", "cloudquery-specs-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create spec directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	readPaths := make([]string, len(files))
+	for i, file := range files {
+		content, ok := stripped[file]
+		if !ok {
+			readPaths[i] = file
+			continue
+		}
+		readPaths[i] = filepath.Join(dir, fmt.Sprintf("%d-%s", i, filepath.Base(file)))
+		if err := os.WriteFile(readPaths[i], content, 0600); err != nil {
+			return nil, nil, fmt.Errorf("failed to copy spec file %s: %w", file, err)
+		}
+	}
+	specReader, err := specs.NewSpecReader(readPaths)
+	if err != nil {
+		msg := err.Error()
+		for i, file := range files {
+			msg = strings.ReplaceAll(msg, readPaths[i], file)
+		}
+		return nil, nil, errors.New(msg)
+	}
+	return specReader, policies, nil
+}
+
+// stripOnErrorPolicies returns the content of a spec file without the on_error field of its destination specs, and
+// the policies they set. Documents that can't be parsed are left as is, for the spec reader to report.
+func stripOnErrorPolicies(data []byte) ([]byte, map[string]string, error) {
+	// documents are split the same way the spec reader does
+	docs := bytes.Split(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")), []byte("\n---\n"))
+	var policies map[string]string
+	for i, doc := range docs {
+		var root yaml.Node
+		if err := yaml.Unmarshal(doc, &root); err != nil || len(root.Content) == 0 {
+			continue
+		}
+		kind := mappingValue(root.Content[0], "kind")
+		spec := mappingValue(root.Content[0], "spec")
+		if kind == nil || kind.Value != specs.KindDestination.String() || spec == nil {
+			continue
+		}
+		key, value := mappingField(spec, onErrorSpecField)
+		if key == nil {
+			continue
+		}
+		var name string
+		if n := mappingValue(spec, "name"); n != nil {
+			name = n.Value
+		}
+		if value.Kind != yaml.ScalarNode || !isOnErrorPolicy(value.Value) {
+			return nil, nil, fmt.Errorf("invalid on_error policy %q for destination %s. Options are: %v", value.Value, name, onErrorPolicies)
+		}
+		if spec.Style&yaml.FlowStyle != 0 || value.Line != key.Line {
+			return nil, nil, fmt.Errorf("on_error of destination %s must be set on its own line", name)
+		}
+		if policies == nil {
+			policies = make(map[string]string)
+		}
+		policies[name] = value.Value
+		lines := bytes.Split(doc, []byte("\n"))
+		docs[i] = bytes.Join(append(lines[:key.Line-1:key.Line-1], lines[key.Line:]...), []byte("\n"))
+	}
+	return bytes.Join(docs, []byte("\n---\n")), policies, nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	_, value := mappingField(node, key)
+	return value
+}
+
+// mappingField returns the key and value nodes of a field of a YAML mapping
+func mappingField(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
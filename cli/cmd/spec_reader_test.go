@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const onErrorSpec = `kind: source
+spec:
+  name: test
+  path: cloudquery/test
+  version: v1.4.5
+  destinations: [test-1, test-2]
+---
+kind: destination
+spec:
+  name: test-1
+  path: cloudquery/test
+  version: v1.3.26
+  on_error: dead_letter # handled by the CLI
+  spec:
+    on_error: kept
+---
+kind: destination
+spec:
+  name: test-2
+  path: cloudquery/test
+  version: v1.3.26
+`
+
+func TestReadSpecs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "spec.yml")
+	require.NoError(t, os.WriteFile(path, []byte(onErrorSpec), 0644))
+
+	specReader, policies, err := readSpecs([]string{dir})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"test-1": onErrorDeadLetter}, policies)
+	require.Len(t, specReader.Destinations, 2)
+	require.Equal(t, map[string]any{"on_error": "kept"}, specReader.GetDestinationByName("test-1").Spec)
+
+	require.NoError(t, os.WriteFile(path, []byte(onErrorSpec+"  unknown: true\n"), 0644))
+	_, _, err = readSpecs([]string{path})
+	require.ErrorContains(t, err, path, "error doesn't refer to the original file")
+}
+
+func TestStripOnErrorPolicies(t *testing.T) {
+	_, _, err := stripOnErrorPolicies([]byte("kind: destination\nspec:\n  name: test\n  on_error: retry\n"))
+	require.ErrorContains(t, err, `invalid on_error policy "retry" for destination test`)
+
+	_, _, err = stripOnErrorPolicies([]byte("kind: destination\nspec: {name: test, on_error: continue}\n"))
+	require.ErrorContains(t, err, "on_error of destination test must be set on its own line")
+
+	content, policies, err := stripOnErrorPolicies([]byte("kind: source\nspec:\n  name: test\n  on_error: continue\n"))
+	require.NoError(t, err)
+	require.Empty(t, policies)
+	require.Equal(t, "kind: source\nspec:\n  name: test\n  on_error: continue\n", string(content))
+}
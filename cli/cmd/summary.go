@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	pluginsSource "github.com/cloudquery/plugin-sdk/plugins/source"
+	"github.com/cloudquery/plugin-sdk/specs"
+)
+
+var tableNamePrefix = []byte(`{"table_name":"`)
+
+// syncSummary is the machine-readable report of a single source sync, written to the file given by --summary-file
+type syncSummary struct {
+	InvocationUUID    string    `json:"invocation_uuid"`
+	SourceName        string    `json:"source_name"`
+	Source            string    `json:"source"`
+	Destinations      []string  `json:"destinations"`
+	SyncTime          time.Time `json:"sync_time"`
+	SyncDuration      float64   `json:"sync_duration_seconds"`
+	MigrationDuration float64   `json:"migration_duration_seconds"`
+	Resources         uint64    `json:"resources"`
+	Errors            uint64    `json:"errors"`
+	Panics            uint64    `json:"panics"`
+	FailedWrites      uint64    `json:"failed_writes"`
+	// FailedDestinations are the destinations that failed to write with an on-error policy other than fail. They are
+	// not closed, so their output isn't finalized.
+	FailedDestinations []string                `json:"failed_destinations,omitempty"`
+	Tables             map[string]tableSummary `json:"tables"`
+	ExitReason         string                  `json:"exit_reason"`
+	Error              string                  `json:"error,omitempty"`
+}
+
+type tableSummary struct {
+	Resources uint64 `json:"resources"`
+	Errors    uint64 `json:"errors,omitempty"`
+	Panics    uint64 `json:"panics,omitempty"`
+}
+
+func newSyncSummary(uid string, sourceSpec specs.Source, destinationsSpecs []specs.Destination) *syncSummary {
+	destinationStrings := make([]string, len(destinationsSpecs))
+	for i := range destinationsSpecs {
+		destinationStrings[i] = destinationsSpecs[i].VersionString()
+	}
+	return &syncSummary{
+		InvocationUUID: uid,
+		SourceName:     sourceSpec.Name,
+		Source:         sourceSpec.VersionString(),
+		Destinations:   destinationStrings,
+		Tables:         make(map[string]tableSummary),
+	}
+}
+
+// resourceSynced counts a resource received from the source towards the resources of its table
+func (s *syncSummary) resourceSynced(resource []byte) {
+	table := resourceTableName(resource)
+	t := s.Tables[table]
+	t.Resources++
+	s.Tables[table] = t
+}
+
+// setMetrics sets the totals of the summary. Newer source plugins aggregate their metrics before sending them,
+// so errors and panics are only set per table when the plugin reports them per table.
+func (s *syncSummary) setMetrics(metrics *pluginsSource.Metrics) {
+	if metrics == nil {
+		return
+	}
+	s.Resources = metrics.TotalResources()
+	s.Errors = metrics.TotalErrors()
+	s.Panics = metrics.TotalPanics()
+	for table, clientMetrics := range metrics.TableClient {
+		if table == "" {
+			continue
+		}
+		t := s.Tables[table]
+		for _, m := range clientMetrics {
+			t.Errors += m.Errors
+			t.Panics += m.Panics
+		}
+		s.Tables[table] = t
+	}
+}
+
+// resourceTableName returns the table name of a resource sent by the source plugin. Resources are JSON encoded
+// with the table name first, so it is read from the prefix to avoid decoding the whole resource.
+func resourceTableName(resource []byte) string {
+	if bytes.HasPrefix(resource, tableNamePrefix) {
+		name := resource[len(tableNamePrefix):]
+		if end := bytes.IndexByte(name, '"'); end != -1 {
+			return string(name[:end])
+		}
+	}
+	var r struct {
+		TableName string `json:"table_name"`
+	}
+	if err := json.Unmarshal(resource, &r); err != nil {
+		return ""
+	}
+	return r.TableName
+}
+
+// writeSyncSummaries writes the summaries to the given file as newline-delimited JSON, one line per source.
+// Nil summaries belong to sources that were never synced and are skipped.
+func writeSyncSummaries(path string, summaries []*syncSummary) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create summary file %s: %w", path, err)
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, summary := range summaries {
+		if summary == nil {
+			continue
+		}
+		if err := enc.Encode(summary); err != nil {
+			return fmt.Errorf("failed to write summary for source %s: %w", summary.SourceName, err)
+		}
+	}
+	return f.Close()
+}
+
+// sourceSummaryLine returns the line reporting the result of the sync of a source in the summary printed after
+// syncing sources in parallel
+func sourceSummaryLine(sourceSpec specs.Source, summary *syncSummary, err error) string {
+	result := "success"
+	if err != nil {
+		result = "failed"
+	}
+	line := sourceSpec.VersionString() + ": " + result
+	if summary != nil {
+		line += fmt.Sprintf(", resources: %d, errors: %d, panics: %d, failed writes: %d, time: %s",
+			summary.Resources, summary.Errors, summary.Panics, summary.FailedWrites, time.Duration(summary.SyncDuration*float64(time.Second)).Truncate(time.Second).String())
+	}
+	if err != nil {
+		line += fmt.Sprintf(": %v", err)
+	}
+	return line
+}
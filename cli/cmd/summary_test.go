@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	pluginsSource "github.com/cloudquery/plugin-sdk/plugins/source"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSyncSummaries(t *testing.T) {
+	sourceSpec := specs.Source{Name: "test", Path: "cloudquery/test", Version: "v1.4.5", Registry: specs.RegistryGithub}
+	destinationsSpecs := []specs.Destination{{Name: "test", Path: "cloudquery/test", Version: "v1.3.26", Registry: specs.RegistryGithub}}
+
+	summary := newSyncSummary("uuid", sourceSpec, destinationsSpecs)
+	for i := 0; i < 5; i++ {
+		summary.resourceSynced([]byte(`{"table_name":"test_table","data":[]}`))
+		summary.resourceSynced([]byte(`{"data":[],"table_name":"test_child_table"}`))
+	}
+	summary.setMetrics(&pluginsSource.Metrics{TableClient: map[string]map[string]*pluginsSource.TableClientMetrics{
+		"test_table": {
+			"client-1": {Resources: 2, Errors: 1},
+			"client-2": {Resources: 3, Panics: 1},
+		},
+		"test_child_table": {
+			"client-1": {Resources: 5},
+		},
+	}})
+	summary.ExitReason = "success"
+
+	summaryFile := path.Join(t.TempDir(), "summary.json")
+	require.NoError(t, writeSyncSummaries(summaryFile, []*syncSummary{summary, nil}))
+
+	f, err := os.Open(summaryFile)
+	require.NoError(t, err)
+	defer f.Close()
+	var got []syncSummary
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s syncSummary
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &s))
+		got = append(got, s)
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Len(t, got, 1)
+	require.Equal(t, "uuid", got[0].InvocationUUID)
+	require.Equal(t, "test (v1.4.5)", got[0].Source)
+	require.Equal(t, []string{"test (v1.3.26)"}, got[0].Destinations)
+	require.Equal(t, uint64(10), got[0].Resources)
+	require.Equal(t, uint64(1), got[0].Errors)
+	require.Equal(t, uint64(1), got[0].Panics)
+	require.Equal(t, tableSummary{Resources: 5, Errors: 1, Panics: 1}, got[0].Tables["test_table"])
+	require.Equal(t, tableSummary{Resources: 5}, got[0].Tables["test_child_table"])
+	require.Equal(t, "success", got[0].ExitReason)
+}
+
+func TestResourceTableName(t *testing.T) {
+	require.Equal(t, "test_table", resourceTableName([]byte(`{"table_name":"test_table","data":[]}`)))
+	require.Equal(t, "test_table", resourceTableName([]byte(`{"data":[], "table_name": "test_table"}`)))
+	require.Equal(t, "", resourceTableName([]byte(`not json`)))
+}
+
+func TestSourceSummaryLine(t *testing.T) {
+	sourceSpec := specs.Source{Name: "test", Registry: specs.RegistryGithub, Path: "cloudquery/test", Version: "v1.4.5"}
+	summary := &syncSummary{Resources: 10, Errors: 1, FailedWrites: 2, SyncDuration: 62.5}
+	require.Equal(t, "test (v1.4.5): success, resources: 10, errors: 1, panics: 0, failed writes: 2, time: 1m2s", sourceSummaryLine(sourceSpec, summary, nil))
+	require.Equal(t, "test (v1.4.5): failed, resources: 10, errors: 1, panics: 0, failed writes: 2, time: 1m2s: boom", sourceSummaryLine(sourceSpec, summary, errors.New("boom")))
+	require.Equal(t, "test (v1.4.5): failed: boom", sourceSummaryLine(sourceSpec, nil, errors.New("boom")))
+}
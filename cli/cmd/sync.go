@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/exp/slices"
@@ -11,7 +14,9 @@ import (
 	"github.com/cloudquery/plugin-sdk/registry"
 	"github.com/cloudquery/plugin-sdk/specs"
 	"github.com/rs/zerolog/log"
+	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -20,8 +25,26 @@ const (
 cloudquery sync ./directory
 # Sync resources from directories and files
 cloudquery sync ./directory ./aws.yml ./pg.yml
+# Sync up to 4 sources from a directory at the same time
+cloudquery sync ./directory --parallel 4
+# Sync only the EC2 tables of the aws source, skipping EC2 images
+cloudquery sync ./directory --source aws --tables "aws_ec2_*" --skip-tables aws_ec2_images
+# Sync with checkpoints, then resume the sync after it was interrupted
+cloudquery sync ./directory --checkpoint
+cloudquery sync ./directory --resume
+# Keep syncing to the other destinations when writing to s3 fails, saving what s3 didn't accept to files
+cloudquery sync ./directory --on-error s3=dead_letter --dead-letter-format csv
+# Keep syncing every hour, the aws source every 6 hours, reloading the spec(s) when they change
+cloudquery sync ./directory --every 1h --source-interval aws=6h --status-address localhost:8080
 `
 	unknownFieldErrorPrefix = "code = InvalidArgument desc = failed to decode spec: json: unknown field "
+
+	// parallelProgressInterval is how often the progress of each source is printed when syncing sources in parallel
+	parallelProgressInterval = 10 * time.Second
+
+	defaultCheckpointBatchSize = 10
+
+	defaultDaemonJitter = 0.1
 )
 
 func NewCmdSync() *cobra.Command {
@@ -34,6 +57,22 @@ func NewCmdSync() *cobra.Command {
 		RunE:    sync,
 	}
 	cmd.Flags().Bool("no-migrate", false, "Disable auto-migration before sync. By default, sync runs a migration before syncing resources.")
+	cmd.Flags().Int("parallel", 1, "Number of sources to sync in parallel. When greater than 1, a failing source doesn't stop the others.")
+	cmd.Flags().String("summary-file", "", "Write a summary of the sync of every source to this file, as newline-delimited JSON")
+	cmd.Flags().String("metrics-address", "", "Expose Prometheus metrics on /metrics at this address (for example localhost:9090) while syncing")
+	cmd.Flags().StringSlice("source", nil, "Only sync the sources with these names. By default, all sources in the spec(s) are synced.")
+	cmd.Flags().StringSlice("tables", nil, "Tables to sync, overriding the tables of the source spec(s). Supports glob patterns such as aws_ec2_*")
+	cmd.Flags().StringSlice("skip-tables", nil, "Tables to skip, in addition to the skip_tables of the source spec(s). Supports glob patterns such as aws_ec2_*")
+	cmd.Flags().Bool("checkpoint", false, "Record the top-level tables that finished syncing in a checkpoint file under --cq-dir, so that an interrupted sync can be resumed with --resume")
+	cmd.Flags().Int("checkpoint-batch-size", defaultCheckpointBatchSize, "Number of top-level tables synced between checkpoints")
+	cmd.Flags().Bool("resume", false, "Resume an interrupted sync from its checkpoint, skipping the tables that were already synced and reusing the original sync time. Implies --checkpoint")
+	cmd.Flags().StringToString("on-error", nil, "What to do when writing to a destination fails, per destination (for example postgresql=continue,s3=dead_letter), overriding the on_error field of the destination specs. One of: fail (the default, stops the sync of the source), continue (keeps syncing to the other destinations), dead_letter (like continue, and writes the resources the destination didn't accept to --dead-letter-dir)")
+	cmd.Flags().String("dead-letter-dir", "", "Directory for the resources destinations with on-error dead_letter failed to accept. Defaults to dead-letter under --cq-dir")
+	cmd.Flags().String("dead-letter-format", defaultDeadLetterFormat, "Format of the dead-letter files. One of: csv, json, parquet")
+	cmd.Flags().Duration("every", 0, "Keep running and sync the sources on this interval (for example 1h), reloading the spec(s) when they change. A run is skipped if the previous run of the source is still going.")
+	cmd.Flags().StringToString("source-interval", nil, "Sync interval of specific sources, overriding --every (for example aws=6h,gcp=12h)")
+	cmd.Flags().Float64("jitter", defaultDaemonJitter, "Random delay added to every scheduled run with --every, as a fraction of the interval of the source")
+	cmd.Flags().String("status-address", "", "Expose /healthz and a /status endpoint with the last run of every source at this address (for example localhost:8080) with --every")
 	return cmd
 }
 
@@ -48,69 +87,337 @@ func sync(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	parallel, err := cmd.Flags().GetInt("parallel")
+	if err != nil {
+		return err
+	}
+
+	summaryFile, err := cmd.Flags().GetString("summary-file")
+	if err != nil {
+		return err
+	}
+
+	metricsAddress, err := cmd.Flags().GetString("metrics-address")
+	if err != nil {
+		return err
+	}
+
+	sourceNames, err := cmd.Flags().GetStringSlice("source")
+	if err != nil {
+		return err
+	}
+	tablesOverride, err := cmd.Flags().GetStringSlice("tables")
+	if err != nil {
+		return err
+	}
+	skipTablesOverride, err := cmd.Flags().GetStringSlice("skip-tables")
+	if err != nil {
+		return err
+	}
+
+	checkpoint, err := cmd.Flags().GetBool("checkpoint")
+	if err != nil {
+		return err
+	}
+	checkpointBatchSize, err := cmd.Flags().GetInt("checkpoint-batch-size")
+	if err != nil {
+		return err
+	}
+	resume, err := cmd.Flags().GetBool("resume")
+	if err != nil {
+		return err
+	}
+
+	onErrorFlags, err := cmd.Flags().GetStringToString("on-error")
+	if err != nil {
+		return err
+	}
+	deadLetterDir, err := cmd.Flags().GetString("dead-letter-dir")
+	if err != nil {
+		return err
+	}
+	if deadLetterDir == "" {
+		deadLetterDir = filepath.Join(cqDir, "dead-letter")
+	}
+	deadLetterFormat, err := cmd.Flags().GetString("dead-letter-format")
+	if err != nil {
+		return err
+	}
+
+	every, err := cmd.Flags().GetDuration("every")
+	if err != nil {
+		return err
+	}
+	sourceIntervalFlags, err := cmd.Flags().GetStringToString("source-interval")
+	if err != nil {
+		return err
+	}
+	jitter, err := cmd.Flags().GetFloat64("jitter")
+	if err != nil {
+		return err
+	}
+	statusAddress, err := cmd.Flags().GetString("status-address")
+	if err != nil {
+		return err
+	}
+
 	ctx := cmd.Context()
+	if parallel < 1 {
+		log.Error().Int("parallel", parallel).Msg("Invalid parallel value")
+		return fmt.Errorf("parallel must be greater than 0, got %d", parallel)
+	}
+	var checkpointOpts *checkpointOptions
+	if checkpoint || resume {
+		if checkpointBatchSize < 1 {
+			log.Error().Int("checkpoint_batch_size", checkpointBatchSize).Msg("Invalid checkpoint batch size")
+			return fmt.Errorf("checkpoint-batch-size must be greater than 0, got %d", checkpointBatchSize)
+		}
+		checkpointOpts = &checkpointOptions{resume: resume, batchSize: checkpointBatchSize}
+	}
+	onError, err := parseOnErrorOptions(onErrorFlags, deadLetterDir, deadLetterFormat)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid on-error options")
+		return err
+	}
+	daemonOpts, err := parseDaemonOptions(every, sourceIntervalFlags, jitter, statusAddress)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid daemon options")
+		return err
+	}
 	log.Info().Strs("args", args).Msg("Loading spec(s)")
 	fmt.Printf("Loading spec(s) from %s\n", strings.Join(args, ", "))
-	specReader, err := specs.NewSpecReader(args)
+	specReader, specPolicies, err := readSpecs(args)
 	if err != nil {
 		return fmt.Errorf("failed to load spec(s) from %s. Error: %w", strings.Join(args, ", "), err)
 	}
+	onError = onError.withSpecPolicies(specPolicies)
+
+	sources, err := selectSources(specReader, sourceNames, tablesOverride, skipTablesOverride)
+	if err != nil {
+		return err
+	}
+	if err := onError.validateDestinations(specReader); err != nil {
+		return err
+	}
 
 	invocationUUID, err := uuid.NewRandom()
 	if err != nil {
 		return fmt.Errorf("failed to generate invocation uuid: %w", err)
 	}
 
-	for _, sourceSpec := range specReader.Sources {
-		if len(sourceSpec.Destinations) == 0 {
-			return fmt.Errorf("no destinations found for source %s", sourceSpec.Name)
+	var exporter *syncMetrics
+	if metricsAddress != "" {
+		exporter = newSyncMetrics()
+		shutdown, err := exporter.serve(metricsAddress)
+		if err != nil {
+			return err
 		}
-		var destinationsSpecs []specs.Destination
-		for _, destination := range sourceSpec.Destinations {
-			spec := specReader.GetDestinationByName(destination)
-			if spec == nil {
-				return fmt.Errorf("failed to find destination %s in source %s", destination, sourceSpec.Name)
-			}
-			destinationsSpecs = append(destinationsSpecs, *spec)
+		defer shutdown()
+		fmt.Printf("Serving metrics on http://%s/metrics\n", metricsAddress)
+	}
+
+	if parallel > 1 || daemonOpts != nil {
+		if err := downloadSyncPlugins(ctx, cqDir, specReader, sources); err != nil {
+			return err
 		}
+	}
 
-		discoveryClient, err := discovery.NewClient(ctx, sourceSpec.Registry, registry.PluginTypeSource, sourceSpec.Path, sourceSpec.Version, discovery.WithDirectory(cqDir))
-		if err != nil {
-			return fmt.Errorf("failed to create discovery client for source %s: %w", sourceSpec.Name, err)
+	if daemonOpts != nil {
+		daemon := &syncDaemon{
+			args:           args,
+			cqDir:          cqDir,
+			sourceNames:    sourceNames,
+			tables:         tablesOverride,
+			skipTables:     skipTablesOverride,
+			noMigrate:      noMigrate,
+			parallel:       parallel,
+			checkpointOpts: checkpointOpts,
+			onError:        onError,
+			exporter:       exporter,
+			summaryFile:    summaryFile,
+			opts:           *daemonOpts,
+			specReader:     specReader,
+			sources:        sources,
 		}
+		return daemon.run(ctx)
+	}
 
-		versions, err := discoveryClient.GetVersions(ctx)
-		if err != nil {
-			if discoveryErr := discoveryClient.Terminate(); discoveryErr != nil {
-				log.Error().Err(discoveryErr).Msg("failed to terminate discovery client")
-				fmt.Println("failed to terminate discovery client:", discoveryErr)
-			}
-			// If we get an error here, we assume that the plugin is not a v1 plugin and we try to sync it as a v0 plugin
-			if err := syncConnectionV0(ctx, cqDir, *sourceSpec, destinationsSpecs, invocationUUID.String(), noMigrate); err != nil {
-				return fmt.Errorf("failed to sync source %s: %w", sourceSpec.Name, err)
-			}
-			continue
+	summaries := make([]*syncSummary, len(sources))
+	defer func() {
+		if summaryFile == "" {
+			return
 		}
-		if err := discoveryClient.Terminate(); err != nil {
-			return fmt.Errorf("failed to terminate discovery client: %w", err)
+		if err := writeSyncSummaries(summaryFile, summaries); err != nil {
+			log.Error().Err(err).Msg("Failed to write sync summary")
+			fmt.Println("failed to write sync summary:", err)
 		}
+	}()
 
-		if slices.Index(versions, "v1") != -1 {
-			if err := syncConnectionV1(ctx, cqDir, *sourceSpec, destinationsSpecs, invocationUUID.String(), noMigrate); err != nil {
-				return fmt.Errorf("failed to sync v1 source %s: %w", sourceSpec.Name, err)
+	if parallel == 1 {
+		for i, sourceSpec := range sources {
+			if summaries[i], err = syncSource(ctx, cqDir, specReader, *sourceSpec, invocationUUID.String(), noMigrate, false, checkpointOpts, onError, exporter); err != nil {
+				return err
 			}
-			continue
 		}
+		return nil
+	}
 
-		if slices.Index(versions, "v0") != -1 {
-			if err := syncConnectionV0(ctx, cqDir, *sourceSpec, destinationsSpecs, invocationUUID.String(), noMigrate); err != nil {
-				return fmt.Errorf("failed to sync v0 source %s: %w", sourceSpec.Name, err)
+	// Sources are independent of each other, so when syncing in parallel a failing source doesn't stop the others.
+	// Errors are collected per source and reported together once all of them are done.
+	fmt.Printf("Syncing %d sources with parallelism %d\n", len(sources), parallel)
+	syncErrors := make([]error, len(sources))
+	g := errgroup.Group{}
+	g.SetLimit(parallel)
+	for i, sourceSpec := range sources {
+		i := i
+		sourceSpec := *sourceSpec
+		g.Go(func() error {
+			summaries[i], syncErrors[i] = syncSource(ctx, cqDir, specReader, sourceSpec, invocationUUID.String(), noMigrate, true, checkpointOpts, onError, exporter)
+			if syncErrors[i] != nil {
+				log.Error().Err(syncErrors[i]).Str("source", sourceSpec.VersionString()).Msg("Sync failed")
 			}
-			continue
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	failed := 0
+	fmt.Println("Sync summary:")
+	for i, sourceSpec := range sources {
+		if syncErrors[i] != nil {
+			failed++
 		}
+		fmt.Println("  " + sourceSummaryLine(*sourceSpec, summaries[i], syncErrors[i]))
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to sync %d out of %d sources", failed, len(sources))
+	}
+	return nil
+}
 
-		return fmt.Errorf("failed to sync source %s, unknown versions %v", sourceSpec.Name, versions)
+// selectSources returns the sources to sync, applying the table selection given on the command line on top of
+// the source specs. When sourceNames is empty all sources are returned.
+func selectSources(specReader *specs.SpecReader, sourceNames []string, tables []string, skipTables []string) ([]*specs.Source, error) {
+	sources := specReader.Sources
+	if len(sourceNames) > 0 {
+		sources = make([]*specs.Source, 0, len(sourceNames))
+		for _, name := range sourceNames {
+			sourceSpec := specReader.GetSourceByName(name)
+			if sourceSpec == nil {
+				return nil, fmt.Errorf("source %s not found in spec(s)", name)
+			}
+			sources = append(sources, sourceSpec)
+		}
+	}
+	for _, sourceSpec := range sources {
+		if len(tables) > 0 {
+			sourceSpec.Tables = tables
+		}
+		if len(skipTables) > 0 {
+			sourceSpec.SkipTables = append(sourceSpec.SkipTables, skipTables...)
+		}
+		if len(tables) > 0 || len(skipTables) > 0 {
+			log.Info().Str("source", sourceSpec.Name).Strs("tables", sourceSpec.Tables).Strs("skip_tables", sourceSpec.SkipTables).Msg("Overriding tables from command line")
+		}
 	}
+	return sources, nil
+}
 
-	return nil
+// syncSource syncs a single source to all of its destinations, picking the sync protocol supported by the source plugin.
+// The returned summary is nil if the sync of the source couldn't be started.
+func syncSource(ctx context.Context, cqDir string, specReader *specs.SpecReader, sourceSpec specs.Source, uid string, noMigrate bool, parallel bool, checkpointOpts *checkpointOptions, onError onErrorOptions, exporter *syncMetrics) (*syncSummary, error) {
+	if len(sourceSpec.Destinations) == 0 {
+		return nil, fmt.Errorf("no destinations found for source %s", sourceSpec.Name)
+	}
+	var destinationsSpecs []specs.Destination
+	for _, destination := range sourceSpec.Destinations {
+		spec := specReader.GetDestinationByName(destination)
+		if spec == nil {
+			return nil, fmt.Errorf("failed to find destination %s in source %s", destination, sourceSpec.Name)
+		}
+		destinationsSpecs = append(destinationsSpecs, *spec)
+	}
+
+	summary := newSyncSummary(uid, sourceSpec, destinationsSpecs)
+	err := syncSourceVersion(ctx, cqDir, sourceSpec, destinationsSpecs, uid, noMigrate, parallel, checkpointOpts, onError, summary, exporter)
+	if summary.ExitReason == "" {
+		// only v1 sources track the exit reason themselves
+		summary.ExitReason = "success"
+		if err != nil {
+			summary.ExitReason = "sync failed"
+		}
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	return summary, err
+}
+
+func syncSourceVersion(ctx context.Context, cqDir string, sourceSpec specs.Source, destinationsSpecs []specs.Destination, uid string, noMigrate bool, parallel bool, checkpointOpts *checkpointOptions, onError onErrorOptions, summary *syncSummary, exporter *syncMetrics) error {
+
+	discoveryClient, err := discovery.NewClient(ctx, sourceSpec.Registry, registry.PluginTypeSource, sourceSpec.Path, sourceSpec.Version, discovery.WithDirectory(cqDir))
+	if err != nil {
+		return fmt.Errorf("failed to create discovery client for source %s: %w", sourceSpec.Name, err)
+	}
+
+	versions, err := discoveryClient.GetVersions(ctx)
+	if err != nil {
+		if discoveryErr := discoveryClient.Terminate(); discoveryErr != nil {
+			log.Error().Err(discoveryErr).Msg("failed to terminate discovery client")
+			fmt.Println("failed to terminate discovery client:", discoveryErr)
+		}
+		// If we get an error here, we assume that the plugin is not a v1 plugin and we try to sync it as a v0 plugin
+		warnCheckpointUnsupported(sourceSpec, checkpointOpts)
+		if err := syncConnectionV0(ctx, cqDir, sourceSpec, destinationsSpecs, uid, noMigrate, parallel, summary); err != nil {
+			return fmt.Errorf("failed to sync source %s: %w", sourceSpec.Name, err)
+		}
+		return nil
+	}
+	if err := discoveryClient.Terminate(); err != nil {
+		return fmt.Errorf("failed to terminate discovery client: %w", err)
+	}
+
+	if slices.Index(versions, "v1") != -1 {
+		if err := syncConnectionV1(ctx, cqDir, sourceSpec, destinationsSpecs, uid, noMigrate, parallel, checkpointOpts, onError, summary, exporter); err != nil {
+			return fmt.Errorf("failed to sync v1 source %s: %w", sourceSpec.Name, err)
+		}
+		return nil
+	}
+
+	if slices.Index(versions, "v0") != -1 {
+		warnCheckpointUnsupported(sourceSpec, checkpointOpts)
+		if err := syncConnectionV0(ctx, cqDir, sourceSpec, destinationsSpecs, uid, noMigrate, parallel, summary); err != nil {
+			return fmt.Errorf("failed to sync v0 source %s: %w", sourceSpec.Name, err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("failed to sync source %s, unknown versions %v", sourceSpec.Name, versions)
+}
+
+func warnCheckpointUnsupported(sourceSpec specs.Source, checkpointOpts *checkpointOptions) {
+	if checkpointOpts == nil {
+		return
+	}
+	log.Warn().Str("source", sourceSpec.VersionString()).Msg("Checkpoints are not supported by this version of the source plugin, syncing all tables")
+	fmt.Printf("Checkpoints are not supported by %s, syncing all tables\n", sourceSpec.VersionString())
+}
+
+// newSyncProgressBar returns the progress bar shown while resources are synced. Progress bars of sources
+// synced in parallel would overwrite each other, so in that case the bar is hidden and progress is
+// reported per source by printSyncProgress instead.
+func newSyncProgressBar(parallel bool) *progressbar.ProgressBar {
+	return progressbar.NewOptions(-1,
+		progressbar.OptionSetDescription("Syncing resources..."),
+		progressbar.OptionSetItsString("resources"),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSetElapsedTime(true),
+		progressbar.OptionShowCount(),
+		progressbar.OptionClearOnFinish(),
+		progressbar.OptionSetVisibility(!parallel),
+	)
+}
+
+func printSyncProgress(sourceSpec specs.Source, totalResources uint64, syncTime time.Time) {
+	fmt.Printf("Syncing %s: %d resources, elapsed %s\n", sourceSpec.VersionString(), totalResources, time.Since(syncTime).Truncate(time.Second).String())
 }
@@ -0,0 +1,442 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// daemonTickInterval is how often the daemon checks for sources that are due to sync
+	daemonTickInterval = time.Second
+	// specReloadInterval is how often the daemon checks the spec files for changes
+	specReloadInterval = 10 * time.Second
+)
+
+// daemonOptions configures sync --every
+type daemonOptions struct {
+	every           time.Duration
+	sourceIntervals map[string]time.Duration
+	// jitter is the maximum fraction of the interval added to every scheduled run, so that sources
+	// with the same interval don't all start at the same time
+	jitter        float64
+	statusAddress string
+}
+
+// parseDaemonOptions returns the options of sync --every, or nil if the sync should only run once
+func parseDaemonOptions(every time.Duration, sourceIntervalFlags map[string]string, jitter float64, statusAddress string) (*daemonOptions, error) {
+	if every == 0 {
+		if len(sourceIntervalFlags) > 0 || statusAddress != "" {
+			return nil, fmt.Errorf("--source-interval and --status-address can only be used with --every")
+		}
+		return nil, nil
+	}
+	if every < 0 {
+		return nil, fmt.Errorf("every must be greater than 0, got %s", every)
+	}
+	if jitter < 0 || jitter > 1 {
+		return nil, fmt.Errorf("jitter must be between 0 and 1, got %v", jitter)
+	}
+	sourceIntervals := make(map[string]time.Duration, len(sourceIntervalFlags))
+	for source, value := range sourceIntervalFlags {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q for source %s: %w", value, source, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("interval of source %s must be greater than 0, got %s", source, interval)
+		}
+		sourceIntervals[source] = interval
+	}
+	return &daemonOptions{every: every, sourceIntervals: sourceIntervals, jitter: jitter, statusAddress: statusAddress}, nil
+}
+
+// sourceStatus is the state of a source reported on the /status endpoint
+type sourceStatus struct {
+	Source        string     `json:"source"`
+	Interval      string     `json:"interval"`
+	Running       bool       `json:"running"`
+	Runs          int        `json:"runs"`
+	SkippedRuns   int        `json:"skipped_runs"`
+	NextRun       time.Time  `json:"next_run"`
+	LastStart     *time.Time `json:"last_start,omitempty"`
+	LastEnd       *time.Time `json:"last_end,omitempty"`
+	LastResult    string     `json:"last_result,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastResources uint64     `json:"last_resources"`
+}
+
+type daemonStatus struct {
+	StartedAt      time.Time      `json:"started_at"`
+	SpecsLoadedAt  time.Time      `json:"specs_loaded_at"`
+	SpecsLoadError string         `json:"specs_load_error,omitempty"`
+	Sources        []sourceStatus `json:"sources"`
+}
+
+type daemonResult struct {
+	source  string
+	summary *syncSummary
+	err     error
+}
+
+// syncDaemon keeps syncing the sources of the specs, each on its own interval, until the context is done.
+// All of its state is owned by the run loop; the status endpoint reads an immutable snapshot published after every change.
+type syncDaemon struct {
+	args           []string
+	cqDir          string
+	sourceNames    []string
+	tables         []string
+	skipTables     []string
+	noMigrate      bool
+	parallel       int
+	checkpointOpts *checkpointOptions
+	onError        onErrorOptions
+	exporter       *syncMetrics
+	summaryFile    string
+	opts           daemonOptions
+
+	specReader      *specs.SpecReader
+	specFingerprint string
+	sources         []*specs.Source
+	status          map[string]*sourceStatus
+	summaries       map[string]*syncSummary
+	running         int
+	snapshot        atomic.Pointer[daemonStatus]
+	startedAt       time.Time
+	specsLoadedAt   time.Time
+	specsLoadError  string
+}
+
+func (d *syncDaemon) run(ctx context.Context) error {
+	fingerprint, err := specsFingerprint(d.args)
+	if err != nil {
+		return err
+	}
+	d.specFingerprint = fingerprint
+	d.startedAt = time.Now().UTC()
+	d.specsLoadedAt = d.startedAt
+	d.status = make(map[string]*sourceStatus)
+	d.summaries = make(map[string]*syncSummary)
+	if err := d.setSources(d.sources); err != nil {
+		return err
+	}
+	d.publish()
+
+	if d.opts.statusAddress != "" {
+		shutdown, err := d.serveStatus(d.opts.statusAddress)
+		if err != nil {
+			return err
+		}
+		defer shutdown()
+		fmt.Printf("Serving status on http://%s/status\n", d.opts.statusAddress)
+	}
+
+	fmt.Printf("Syncing %d sources every %s. Press Ctrl+C to stop\n", len(d.sources), d.opts.every)
+	log.Info().Int("sources", len(d.sources)).Dur("every", d.opts.every).Msg("Starting sync daemon")
+	results := make(chan daemonResult)
+	tick := time.NewTicker(daemonTickInterval)
+	defer tick.Stop()
+	reload := time.NewTicker(specReloadInterval)
+	defer reload.Stop()
+	for {
+		d.startDueSources(ctx, results)
+		d.publish()
+		select {
+		case <-ctx.Done():
+			if d.running > 0 {
+				fmt.Printf("Waiting for %d running syncs to stop\n", d.running)
+			}
+			for d.running > 0 {
+				d.finish(<-results)
+			}
+			log.Info().Msg("Sync daemon stopped")
+			return nil
+		case r := <-results:
+			d.finish(r)
+		case <-tick.C:
+		case <-reload.C:
+			d.reloadSpecs(ctx)
+		}
+	}
+}
+
+// setSources replaces the synced sources. Sources that are new are scheduled to sync right away, and the
+// state of sources that were removed is dropped once they are no longer running.
+func (d *syncDaemon) setSources(sources []*specs.Source) error {
+	names := make(map[string]bool, len(sources))
+	for _, sourceSpec := range sources {
+		names[sourceSpec.Name] = true
+	}
+	for name := range d.opts.sourceIntervals {
+		if !names[name] {
+			return fmt.Errorf("source %s given in --source-interval not found in spec(s)", name)
+		}
+	}
+	for _, sourceSpec := range sources {
+		if _, ok := d.status[sourceSpec.Name]; !ok {
+			d.status[sourceSpec.Name] = &sourceStatus{Source: sourceSpec.Name, NextRun: time.Now().UTC()}
+		}
+		d.status[sourceSpec.Name].Interval = d.interval(sourceSpec.Name).String()
+	}
+	for name, status := range d.status {
+		if !names[name] && !status.Running {
+			delete(d.status, name)
+			delete(d.summaries, name)
+		}
+	}
+	d.sources = sources
+	return nil
+}
+
+func (d *syncDaemon) interval(source string) time.Duration {
+	if interval, ok := d.opts.sourceIntervals[source]; ok {
+		return interval
+	}
+	return d.opts.every
+}
+
+// nextRun returns the time of the next scheduled run of the source, with a random jitter of up to the
+// configured fraction of its interval
+func (d *syncDaemon) nextRun(source string) time.Time {
+	interval := d.interval(source)
+	jitter := time.Duration(rand.Float64() * d.opts.jitter * float64(interval)) // #nosec G404 -- not security sensitive
+	return time.Now().UTC().Add(interval + jitter)
+}
+
+// startDueSources starts the sync of every source whose next run is due, as long as there are fewer than
+// --parallel syncs running. A source whose previous run is still going skips the run.
+func (d *syncDaemon) startDueSources(ctx context.Context, results chan<- daemonResult) {
+	if ctx.Err() != nil {
+		return
+	}
+	now := time.Now().UTC()
+	for _, sourceSpec := range d.sources {
+		status := d.status[sourceSpec.Name]
+		if now.Before(status.NextRun) {
+			continue
+		}
+		if status.Running {
+			status.SkippedRuns++
+			status.NextRun = d.nextRun(sourceSpec.Name)
+			log.Warn().Str("source", sourceSpec.Name).Time("next_run", status.NextRun).Msg("Previous sync is still running, skipping run")
+			fmt.Printf("Skipping sync of %s, the previous sync is still running\n", sourceSpec.Name)
+			continue
+		}
+		if d.running >= d.parallel {
+			// stays due until another sync finishes
+			continue
+		}
+		status.Running = true
+		status.Runs++
+		start := time.Now().UTC()
+		status.LastStart = &start
+		// intervals are measured from the start of a run, so a run that takes longer than the interval skips the next one
+		status.NextRun = d.nextRun(sourceSpec.Name)
+		d.running++
+
+		sourceSpec := *sourceSpec
+		specReader := d.specReader
+		onError := d.onError
+		go func() {
+			// every run gets its own context, so that what the sync keeps until the context is done is released
+			// when the run ends rather than when the daemon stops
+			runCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			var summary *syncSummary
+			uid, err := uuid.NewRandom()
+			if err == nil {
+				summary, err = syncSource(runCtx, d.cqDir, specReader, sourceSpec, uid.String(), d.noMigrate, true, d.checkpointOpts, onError, d.exporter)
+			} else {
+				err = fmt.Errorf("failed to generate invocation uuid: %w", err)
+			}
+			results <- daemonResult{source: sourceSpec.Name, summary: summary, err: err}
+		}()
+	}
+}
+
+func (d *syncDaemon) finish(r daemonResult) {
+	d.running--
+	status, ok := d.status[r.source]
+	if !ok {
+		return
+	}
+	end := time.Now().UTC()
+	status.Running = false
+	status.LastEnd = &end
+	status.LastResources = 0
+	if r.summary != nil {
+		status.LastResources = r.summary.Resources
+		d.summaries[r.source] = r.summary
+	}
+	if r.err != nil {
+		status.LastResult = "failed"
+		status.LastError = r.err.Error()
+		log.Error().Err(r.err).Str("source", r.source).Time("next_run", status.NextRun).Msg("Sync failed")
+		fmt.Printf("Sync of %s failed: %v. Next run at %s\n", r.source, r.err, status.NextRun.Format(time.RFC3339))
+	} else {
+		status.LastResult = "success"
+		status.LastError = ""
+		log.Info().Str("source", r.source).Time("next_run", status.NextRun).Msg("Sync finished")
+		fmt.Printf("Sync of %s finished. Next run at %s\n", r.source, status.NextRun.Format(time.RFC3339))
+	}
+	if _, ok := d.findSource(r.source); !ok {
+		// the source was removed from the specs while it was running
+		delete(d.status, r.source)
+		delete(d.summaries, r.source)
+	}
+	d.writeSummaries()
+}
+
+func (d *syncDaemon) findSource(name string) (*specs.Source, bool) {
+	for _, sourceSpec := range d.sources {
+		if sourceSpec.Name == name {
+			return sourceSpec, true
+		}
+	}
+	return nil, false
+}
+
+// writeSummaries writes the summary of the last run of every source to --summary-file
+func (d *syncDaemon) writeSummaries() {
+	if d.summaryFile == "" {
+		return
+	}
+	summaries := make([]*syncSummary, len(d.sources))
+	for i, sourceSpec := range d.sources {
+		summaries[i] = d.summaries[sourceSpec.Name]
+	}
+	if err := writeSyncSummaries(d.summaryFile, summaries); err != nil {
+		log.Error().Err(err).Msg("Failed to write sync summary")
+		fmt.Println("failed to write sync summary:", err)
+	}
+}
+
+// reloadSpecs loads the specs again if the spec files changed. Invalid specs are reported and the
+// previous specs are kept, so that a bad edit doesn't stop the daemon.
+func (d *syncDaemon) reloadSpecs(ctx context.Context) {
+	fingerprint, err := specsFingerprint(d.args)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to check spec(s) for changes")
+		return
+	}
+	if fingerprint == d.specFingerprint {
+		return
+	}
+	log.Info().Strs("args", d.args).Msg("Spec(s) changed, reloading")
+	fmt.Printf("Reloading spec(s) from %s\n", strings.Join(d.args, ", "))
+	if err := d.loadSpecs(ctx); err != nil {
+		d.specsLoadError = err.Error()
+		log.Error().Err(err).Msg("Failed to reload spec(s), keeping the previous spec(s)")
+		fmt.Println("Failed to reload spec(s), keeping the previous spec(s):", err)
+		return
+	}
+	// the fingerprint is only updated once the specs were loaded, so that a failed reload is tried again
+	d.specFingerprint = fingerprint
+	d.specsLoadedAt = time.Now().UTC()
+	d.specsLoadError = ""
+}
+
+func (d *syncDaemon) loadSpecs(ctx context.Context) error {
+	specReader, specPolicies, err := readSpecs(d.args)
+	if err != nil {
+		return fmt.Errorf("failed to load spec(s) from %s. Error: %w", strings.Join(d.args, ", "), err)
+	}
+	sources, err := selectSources(specReader, d.sourceNames, d.tables, d.skipTables)
+	if err != nil {
+		return err
+	}
+	if err := d.onError.validateDestinations(specReader); err != nil {
+		return err
+	}
+	// plugins of new versions are downloaded before any sync uses them, as syncs of different sources run concurrently
+	if err := downloadSyncPlugins(ctx, d.cqDir, specReader, sources); err != nil {
+		return err
+	}
+	if err := d.setSources(sources); err != nil {
+		return err
+	}
+	d.specReader = specReader
+	d.onError = d.onError.withSpecPolicies(specPolicies)
+	return nil
+}
+
+func (d *syncDaemon) publish() {
+	status := &daemonStatus{
+		StartedAt:      d.startedAt,
+		SpecsLoadedAt:  d.specsLoadedAt,
+		SpecsLoadError: d.specsLoadError,
+		Sources:        make([]sourceStatus, 0, len(d.status)),
+	}
+	for _, sourceSpec := range d.sources {
+		status.Sources = append(status.Sources, *d.status[sourceSpec.Name])
+	}
+	d.snapshot.Store(status)
+}
+
+// serveStatus starts an HTTP server with a /healthz liveness endpoint and a /status endpoint reporting the
+// result of the last run of every source. The returned function shuts the server down.
+func (d *syncDaemon) serveStatus(address string) (func(), error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on status address %s: %w", address, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(d.snapshot.Load()); err != nil {
+			log.Warn().Err(err).Msg("Failed to write status")
+		}
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("Status server failed")
+		}
+	}()
+	log.Info().Str("address", listener.Addr().String()).Msg("Serving status")
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to shut down status server")
+		}
+	}, nil
+}
+
+// specsFingerprint returns a hash of the names and contents of the spec files, used to detect changes
+func specsFingerprint(args []string) (string, error) {
+	files, err := specFiles(args)
+	if err != nil {
+		return "", fmt.Errorf("failed to list spec files: %w", err)
+	}
+	h := sha256.New()
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read spec file %s: %w", file, err)
+		}
+		h.Write([]byte(file))
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDaemonOptions(t *testing.T) {
+	opts, err := parseDaemonOptions(0, nil, defaultDaemonJitter, "")
+	require.NoError(t, err)
+	require.Nil(t, opts)
+
+	_, err = parseDaemonOptions(0, nil, defaultDaemonJitter, "localhost:8080")
+	require.ErrorContains(t, err, "can only be used with --every")
+
+	_, err = parseDaemonOptions(time.Hour, nil, 2, "")
+	require.ErrorContains(t, err, "jitter must be between 0 and 1")
+
+	_, err = parseDaemonOptions(time.Hour, map[string]string{"aws": "never"}, defaultDaemonJitter, "")
+	require.ErrorContains(t, err, `invalid interval "never" for source aws`)
+
+	opts, err = parseDaemonOptions(time.Hour, map[string]string{"aws": "6h"}, defaultDaemonJitter, "localhost:8080")
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, opts.every)
+	require.Equal(t, map[string]time.Duration{"aws": 6 * time.Hour}, opts.sourceIntervals)
+}
+
+func TestSyncDaemonSchedule(t *testing.T) {
+	d := &syncDaemon{
+		parallel: 1,
+		opts: daemonOptions{
+			every:           time.Hour,
+			sourceIntervals: map[string]time.Duration{"gcp": 6 * time.Hour},
+			jitter:          0.5,
+		},
+		status:    make(map[string]*sourceStatus),
+		summaries: make(map[string]*syncSummary),
+	}
+	require.ErrorContains(t, d.setSources([]*specs.Source{{Name: "aws"}}), "source gcp given in --source-interval not found")
+	require.Empty(t, d.status)
+
+	require.NoError(t, d.setSources([]*specs.Source{{Name: "aws"}, {Name: "gcp"}}))
+	require.Equal(t, "1h0m0s", d.status["aws"].Interval)
+	require.Equal(t, "6h0m0s", d.status["gcp"].Interval)
+
+	for i := 0; i < 10; i++ {
+		next := time.Until(d.nextRun("gcp"))
+		require.GreaterOrEqual(t, next, 6*time.Hour-time.Minute)
+		require.LessOrEqual(t, next, 9*time.Hour)
+	}
+
+	// a source whose previous run is still going skips the run, and a source that is due waits for a free slot
+	d.status["aws"].Running = true
+	d.running = 1
+	d.startDueSources(context.Background(), nil)
+	require.Equal(t, 1, d.status["aws"].SkippedRuns)
+	require.True(t, d.status["aws"].NextRun.After(time.Now()))
+	require.Equal(t, 0, d.status["gcp"].Runs)
+
+	d.finish(daemonResult{source: "aws", summary: &syncSummary{Resources: 5}})
+	require.Equal(t, 0, d.running)
+	require.False(t, d.status["aws"].Running)
+	require.Equal(t, "success", d.status["aws"].LastResult)
+	require.Equal(t, uint64(5), d.status["aws"].LastResources)
+
+	// removed sources are dropped once they are no longer running
+	require.NoError(t, d.setSources([]*specs.Source{{Name: "gcp"}}))
+	require.NotContains(t, d.status, "aws")
+}
+
+func TestSpecsFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	specFile := path.Join(dir, "spec.yml")
+	require.NoError(t, os.WriteFile(specFile, []byte("kind: source"), 0644))
+	before, err := specsFingerprint([]string{dir})
+	require.NoError(t, err)
+
+	unchanged, err := specsFingerprint([]string{dir})
+	require.NoError(t, err)
+	require.Equal(t, before, unchanged)
+
+	require.NoError(t, os.WriteFile(specFile, []byte("kind: destination"), 0644))
+	after, err := specsFingerprint([]string{dir})
+	require.NoError(t, err)
+	require.NotEqual(t, before, after)
+}
+
+func TestSyncDaemonReloadSpecsFailed(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, "spec.yml"), []byte("kind: source"), 0644))
+	d := &syncDaemon{args: []string{dir}, specFingerprint: "previous"}
+
+	d.reloadSpecs(context.Background())
+	require.NotEmpty(t, d.specsLoadError)
+	// the specs are loaded again on the next reload, even if they didn't change
+	require.Equal(t, "previous", d.specFingerprint)
+}
@@ -6,11 +6,12 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/cloudquery/plugin-sdk/specs"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
 )
 
-func getSyncCommand(t *testing.T, config string) (*cobra.Command, string) {
+func getSyncCommand(t *testing.T, config string, extraArgs ...string) (*cobra.Command, string) {
 	t.Helper()
 
 	_, filename, _, _ := runtime.Caller(0)
@@ -19,7 +20,7 @@ func getSyncCommand(t *testing.T, config string) (*cobra.Command, string) {
 	tmpDir := t.TempDir()
 	logFileName := path.Join(tmpDir, "cloudquery.log")
 	cmd := NewCmdRoot()
-	cmd.SetArgs([]string{"sync", testConfig, "--cq-dir", tmpDir, "--log-file-name", logFileName})
+	cmd.SetArgs(append([]string{"sync", testConfig, "--cq-dir", tmpDir, "--log-file-name", logFileName}, extraArgs...))
 	return cmd, tmpDir
 }
 
@@ -27,6 +28,7 @@ func TestSync(t *testing.T) {
 	configs := []struct {
 		name                       string
 		config                     string
+		args                       []string
 		err                        string
 		logMessages                []string
 		wantSourcePluginCache      bool
@@ -52,12 +54,34 @@ func TestSync(t *testing.T) {
 			wantSourcePluginCache:      true,
 			wantDestinationPluginCache: true,
 		},
+		{
+			name:   "should sync multiple sources in parallel",
+			config: "multiple-sources-destinations.yml",
+			args:   []string{"--parallel", "2"},
+			logMessages: []string{
+				`Start sync destinations=\["test\-1.*?","test\-2.*?"\] module=cli source="test\-1.*?"`,
+				`Start sync destinations=\["test\-2.*?","test\-1.*?"\] module=cli source="test\-2.*?"`},
+			wantSourcePluginCache:      true,
+			wantDestinationPluginCache: true,
+		},
+		{
+			name:   "should fail with invalid parallel value",
+			config: "sync-success.yml",
+			args:   []string{"--parallel", "0"},
+			err:    "parallel must be greater than 0",
+		},
+		{
+			name:   "should fail with invalid checkpoint batch size",
+			config: "sync-success.yml",
+			args:   []string{"--checkpoint", "--checkpoint-batch-size", "0"},
+			err:    "checkpoint-batch-size must be greater than 0",
+		},
 	}
 
 	for _, tc := range configs {
 		t.Run(tc.name, func(t *testing.T) {
 			defer CloseLogFile()
-			cmd, cqDir := getSyncCommand(t, tc.config)
+			cmd, cqDir := getSyncCommand(t, tc.config, tc.args...)
 			commandError := cmd.Execute()
 
 			// check that log was written and contains some lines from the plugin
@@ -98,3 +122,27 @@ func TestSync(t *testing.T) {
 		})
 	}
 }
+
+func TestSelectSources(t *testing.T) {
+	_, filename, _, _ := runtime.Caller(0)
+	testConfig := path.Join(path.Dir(filename), "testdata", "multiple-sources-destinations.yml")
+
+	specReader, err := specs.NewSpecReader([]string{testConfig})
+	require.NoError(t, err)
+	sources, err := selectSources(specReader, nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	require.Equal(t, []string{"*"}, sources[0].Tables)
+
+	specReader, err = specs.NewSpecReader([]string{testConfig})
+	require.NoError(t, err)
+	sources, err = selectSources(specReader, []string{"test-2"}, []string{"test_*"}, []string{"test_skip_*"})
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	require.Equal(t, "test-2", sources[0].Name)
+	require.Equal(t, []string{"test_*"}, sources[0].Tables)
+	require.Equal(t, []string{"test_skip_*"}, sources[0].SkipTables)
+
+	_, err = selectSources(specReader, []string{"unknown"}, nil, nil)
+	require.ErrorContains(t, err, "source unknown not found in spec(s)")
+}
@@ -9,7 +9,7 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-func syncConnectionV0(ctx context.Context, cqDir string, sourceSpec specs.Source, destinationsSpecs []specs.Destination, uid string, noMigrate bool) error {
+func syncConnectionV0(ctx context.Context, cqDir string, sourceSpec specs.Source, destinationsSpecs []specs.Destination, uid string, noMigrate bool, parallel bool, summary *syncSummary) error {
 	opts := []source.ClientOption{
 		source.WithLogger(log.Logger),
 		source.WithDirectory(cqDir),
@@ -35,11 +35,11 @@ func syncConnectionV0(ctx context.Context, cqDir string, sourceSpec specs.Source
 	}
 	switch v {
 	case 1:
-		if err := syncConnectionV0_1(ctx, cqDir, sourceClient, sourceSpec, destinationsSpecs, uid, noMigrate); err != nil {
+		if err := syncConnectionV0_1(ctx, cqDir, sourceClient, sourceSpec, destinationsSpecs, uid, noMigrate, parallel, summary); err != nil {
 			return err
 		}
 	case 2:
-		if err := syncConnectionV0_2(ctx, cqDir, sourceClient, sourceSpec, destinationsSpecs, uid, noMigrate); err != nil {
+		if err := syncConnectionV0_2(ctx, cqDir, sourceClient, sourceSpec, destinationsSpecs, uid, noMigrate, parallel, summary); err != nil {
 			return err
 		}
 	default:
@@ -10,17 +10,17 @@ import (
 	"github.com/cloudquery/plugin-sdk/clients/source/v0"
 	"github.com/cloudquery/plugin-sdk/specs"
 	"github.com/rs/zerolog/log"
-	"github.com/schollz/progressbar/v3"
 	"golang.org/x/sync/errgroup"
 )
 
-func syncConnectionV0_1(ctx context.Context, cqDir string, sourceClient *source.Client, sourceSpec specs.Source, destinationsSpecs []specs.Destination, uid string, noMigrate bool) error {
+func syncConnectionV0_1(ctx context.Context, cqDir string, sourceClient *source.Client, sourceSpec specs.Source, destinationsSpecs []specs.Destination, uid string, noMigrate bool, parallel bool, summary *syncSummary) error {
 	var err error
 	destinationNames := make([]string, len(destinationsSpecs))
 	for i := range destinationsSpecs {
 		destinationNames[i] = destinationsSpecs[i].Name
 	}
 	syncTime := time.Now().UTC()
+	summary.SyncTime = syncTime
 
 	log.Info().Str("source", sourceSpec.Name).Strs("destinations", destinationNames).Time("sync_time", syncTime).Msg("Start sync")
 	defer log.Info().Str("source", sourceSpec.Name).Strs("destinations", destinationNames).Time("sync_time", syncTime).Msg("End sync")
@@ -82,14 +82,7 @@ func syncConnectionV0_1(ctx context.Context, cqDir string, sourceClient *source.
 		return nil
 	})
 
-	bar := progressbar.NewOptions(-1,
-		progressbar.OptionSetDescription("Syncing resources..."),
-		progressbar.OptionSetItsString("resources"),
-		progressbar.OptionShowIts(),
-		progressbar.OptionSetElapsedTime(true),
-		progressbar.OptionShowCount(),
-		progressbar.OptionClearOnFinish(),
-	)
+	bar := newSyncProgressBar(parallel)
 	failedWrites := uint64(0)
 	totalResources := uint64(0)
 	destSubscriptions := make([]chan []byte, len(sourceSpec.Destinations))
@@ -114,9 +107,14 @@ func syncConnectionV0_1(ctx context.Context, cqDir string, sourceClient *source.
 	}
 
 	g.Go(func() error {
+		lastProgress := time.Now()
 		for resource := range resources {
 			totalResources++
 			_ = bar.Add(1)
+			if parallel && time.Since(lastProgress) >= parallelProgressInterval {
+				printSyncProgress(sourceSpec, totalResources, syncTime)
+				lastProgress = time.Now()
+			}
 			for i := range destSubscriptions {
 				select {
 				case <-gctx.Done():
@@ -135,7 +133,7 @@ func syncConnectionV0_1(ctx context.Context, cqDir string, sourceClient *source.
 		_ = bar.Finish()
 		return err
 	}
-	summary, err := sourceClient.GetSyncSummary(ctx)
+	sourceSummary, err := sourceClient.GetSyncSummary(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get sync summary: %w", err)
 	}
@@ -143,14 +141,19 @@ func syncConnectionV0_1(ctx context.Context, cqDir string, sourceClient *source.
 	syncTimeTook := time.Since(syncTime)
 
 	fmt.Println("Sync completed successfully.")
-	fmt.Printf("Summary: resources: %d, errors: %d, panic: %d, failed_writes: %d, time: %s\n", summary.Resources, summary.Errors, summary.Panics, failedWrites, syncTimeTook.Truncate(time.Second).String())
+	fmt.Printf("Summary: resources: %d, errors: %d, panic: %d, failed_writes: %d, time: %s\n", sourceSummary.Resources, sourceSummary.Errors, sourceSummary.Panics, failedWrites, syncTimeTook.Truncate(time.Second).String())
 	log.Info().Str("source", sourceSpec.Name).Strs("destinations", sourceSpec.Destinations).
-		Uint64("resources", totalResources).Uint64("errors", summary.Errors).Uint64("panic", summary.Panics).Uint64("failed_writes", failedWrites).Float64("time_took", syncTimeTook.Seconds()).Msg("Sync completed successfully")
+		Uint64("resources", totalResources).Uint64("errors", sourceSummary.Errors).Uint64("panic", sourceSummary.Panics).Uint64("failed_writes", failedWrites).Float64("time_took", syncTimeTook.Seconds()).Msg("Sync completed successfully")
+	summary.Resources = sourceSummary.Resources
+	summary.Errors = sourceSummary.Errors
+	summary.Panics = sourceSummary.Panics
+	summary.FailedWrites = failedWrites
+	summary.SyncDuration = syncTimeTook.Seconds()
 
 	// Send analytics, if activated. We only send if the source plugin registry is GitHub, mostly to avoid sending data from development machines.
 	if analyticsClient != nil && sourceSpec.Registry == specs.RegistryGithub {
 		log.Info().Msg("Sending sync summary to " + analyticsClient.Host())
-		if err := analyticsClient.SendSyncSummary(ctx, sourceSpec, destinationsSpecs, uid, *summary); err != nil {
+		if err := analyticsClient.SendSyncSummary(ctx, sourceSpec, destinationsSpecs, uid, *sourceSummary); err != nil {
 			log.Warn().Err(err).Msg("Failed to send sync summary")
 		}
 	}
@@ -10,17 +10,17 @@ import (
 	"github.com/cloudquery/plugin-sdk/schema"
 	"github.com/cloudquery/plugin-sdk/specs"
 	"github.com/rs/zerolog/log"
-	"github.com/schollz/progressbar/v3"
 	"golang.org/x/sync/errgroup"
 )
 
-func syncConnectionV0_2(ctx context.Context, cqDir string, sourceClient *source.Client, sourceSpec specs.Source, destinationsSpecs []specs.Destination, uid string, noMigrate bool) error {
+func syncConnectionV0_2(ctx context.Context, cqDir string, sourceClient *source.Client, sourceSpec specs.Source, destinationsSpecs []specs.Destination, uid string, noMigrate bool, parallel bool, summary *syncSummary) error {
 	var err error
 	destinationStrings := make([]string, len(destinationsSpecs))
 	for i := range destinationsSpecs {
 		destinationStrings[i] = destinationsSpecs[i].VersionString()
 	}
 	syncTime := time.Now().UTC()
+	summary.SyncTime = syncTime
 
 	log.Info().Str("source", sourceSpec.VersionString()).Strs("destinations", destinationStrings).Time("sync_time", syncTime).Msg("Start sync")
 	defer log.Info().Str("source", sourceSpec.VersionString()).Strs("destinations", destinationStrings).Time("sync_time", syncTime).Msg("End sync")
@@ -92,14 +92,7 @@ func syncConnectionV0_2(ctx context.Context, cqDir string, sourceClient *source.
 	for i := range destSubscriptions {
 		destSubscriptions[i] = make(chan []byte)
 	}
-	bar := progressbar.NewOptions(-1,
-		progressbar.OptionSetDescription("Syncing resources..."),
-		progressbar.OptionSetItsString("resources"),
-		progressbar.OptionShowIts(),
-		progressbar.OptionSetElapsedTime(true),
-		progressbar.OptionShowCount(),
-		progressbar.OptionClearOnFinish(),
-	)
+	bar := newSyncProgressBar(parallel)
 	failedWrites := uint64(0)
 	totalResources := uint64(0)
 	for i, destination := range destinationsSpecs {
@@ -122,6 +115,7 @@ func syncConnectionV0_2(ctx context.Context, cqDir string, sourceClient *source.
 
 	g.Go(func() error {
 		t := time.NewTicker(1 * time.Second)
+		lastProgress := time.Now()
 		defer func() {
 			for i := range destSubscriptions {
 				close(destSubscriptions[i])
@@ -145,6 +139,10 @@ func syncConnectionV0_2(ctx context.Context, cqDir string, sourceClient *source.
 				}
 			case <-t.C:
 				_ = bar.Add(0)
+				if parallel && time.Since(lastProgress) >= parallelProgressInterval {
+					printSyncProgress(sourceSpec, totalResources, syncTime)
+					lastProgress = time.Now()
+				}
 			case <-gctx.Done():
 				return nil
 			}
@@ -164,6 +162,9 @@ func syncConnectionV0_2(ctx context.Context, cqDir string, sourceClient *source.
 	}
 
 	fmt.Printf("Sync completed successfully. Resources: %d, Errors: %d, Panics: %d, Time: %s\n", metrics.TotalResources(), metrics.TotalErrors(), metrics.TotalPanics(), syncTimeTook.Truncate(time.Second).String())
+	summary.setMetrics(metrics)
+	summary.FailedWrites = failedWrites
+	summary.SyncDuration = syncTimeTook.Seconds()
 
 	// Send analytics, if activated. We only send if the source plugin registry is GitHub, mostly to avoid sending data from development machines.
 	if analyticsClient != nil && sourceSpec.Registry == specs.RegistryGithub {
@@ -3,22 +3,29 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"sync/atomic"
+	"path/filepath"
 	"time"
 
 	"github.com/cloudquery/plugin-sdk/clients/source/v1"
 	pluginsSource "github.com/cloudquery/plugin-sdk/plugins/source"
+	"github.com/cloudquery/plugin-sdk/schema"
 	"github.com/cloudquery/plugin-sdk/specs"
 	"github.com/rs/zerolog/log"
-	"github.com/schollz/progressbar/v3"
 	"golang.org/x/sync/errgroup"
 )
 
-func syncConnectionV1(ctx context.Context, cqDir string, sourceSpec specs.Source, destinationsSpecs []specs.Destination, uid string, noMigrate bool) error {
+func syncConnectionV1(ctx context.Context, cqDir string, sourceSpec specs.Source, destinationsSpecs []specs.Destination, uid string, noMigrate bool, parallel bool, checkpointOpts *checkpointOptions, onError onErrorOptions, summary *syncSummary, exporter *syncMetrics) error {
 	var metrics *pluginsSource.Metrics
 	exitReason := "unknown"
 	canceled := false
+	syncStart := time.Now()
 	defer func() {
+		summary.ExitReason = exitReason
+		exporter.syncFinished(sourceSpec, exitReason == "success")
+		summary.setMetrics(metrics)
+		if !summary.SyncTime.IsZero() {
+			summary.SyncDuration = time.Since(syncStart).Seconds()
+		}
 		// Send analytics, if activated.
 		if analyticsClient != nil {
 			log.Info().Msg("Sending sync summary to " + analyticsClient.Host())
@@ -54,12 +61,39 @@ func syncConnectionV1(ctx context.Context, cqDir string, sourceSpec specs.Source
 	}()
 
 	syncTime := time.Now().UTC()
+	var checkpoint *syncCheckpoint
+	if checkpointOpts != nil {
+		if checkpointOpts.resume {
+			checkpoint, err = loadSyncCheckpoint(cqDir, sourceSpec)
+			if err != nil {
+				exitReason = "failed to load checkpoint"
+				return err
+			}
+			if checkpoint != nil {
+				// reuse the original sync time, so that DeleteStale only removes resources older than the interrupted sync
+				syncTime = checkpoint.SyncTime
+				log.Info().Str("source", sourceSpec.VersionString()).Str("invocation_uuid", checkpoint.InvocationUUID).Strs("completed_tables", checkpoint.CompletedTables).Time("sync_time", syncTime).Msg("Resuming sync from checkpoint")
+				fmt.Printf("Resuming sync for %s from checkpoint, skipping %d completed tables\n", sourceSpec.VersionString(), len(checkpoint.CompletedTables))
+			} else {
+				log.Info().Str("source", sourceSpec.VersionString()).Msg("No checkpoint found, starting a new sync")
+			}
+		}
+		if checkpoint == nil {
+			checkpoint, err = newSyncCheckpoint(cqDir, sourceSpec, uid, syncTime)
+			if err != nil {
+				exitReason = "failed to create checkpoint"
+				return err
+			}
+		}
+	}
+	summary.SyncTime = syncTime
 	destinationStrings := make([]string, len(destinationsSpecs))
 	for i := range destinationsSpecs {
 		destinationStrings[i] = destinationsSpecs[i].VersionString()
 	}
 
 	log.Info().Str("source", sourceSpec.VersionString()).Strs("destinations", destinationStrings).Time("sync_time", syncTime).Msg("Start sync")
+	exporter.syncStarted(sourceSpec)
 	defer log.Info().Str("source", sourceSpec.VersionString()).Strs("destinations", destinationStrings).Time("sync_time", syncTime).Msg("End sync")
 
 	destCtx, destCancel := context.WithCancel(context.Background())
@@ -77,8 +111,14 @@ func syncConnectionV1(ctx context.Context, cqDir string, sourceSpec specs.Source
 		destClients.Close()
 	}()
 
+	syncDone := make(chan struct{})
+	defer close(syncDone)
 	go func() {
-		<-ctx.Done()
+		select {
+		case <-ctx.Done():
+		case <-syncDone:
+			return
+		}
 		if metrics == nil {
 			// If we didn't get metrics because sync got interrupted, try to get them
 			// now, before closing the source client.
@@ -117,6 +157,7 @@ func syncConnectionV1(ctx context.Context, cqDir string, sourceSpec specs.Source
 			}
 		}
 		migrateTimeTook := time.Since(migrateStart)
+		summary.MigrationDuration = migrateTimeTook.Seconds()
 		fmt.Printf("Migration completed successfully.\n")
 		log.Info().
 			Str("source", sourceSpec.VersionString()).
@@ -126,102 +167,252 @@ func syncConnectionV1(ctx context.Context, cqDir string, sourceSpec specs.Source
 			Msg("End migration")
 	}
 
-	resources := make(chan []byte)
-	g, gctx := errgroup.WithContext(sourceCtx)
+	// Without checkpointing all tables are synced at once. With checkpointing the top-level tables are synced in
+	// batches, and each batch that was written to all destinations is recorded in the checkpoint.
+	batches := []schema.Tables{tables}
+	if checkpoint != nil {
+		batches = checkpointBatches(tables, checkpoint, checkpointOpts.batchSize)
+	}
+
+	pollCtx, pollCancel := context.WithCancel(sourceCtx)
+	defer pollCancel()
+	go exporter.poll(pollCtx, sourceSpec, sourceClient, destinationsSpecs, destClients)
 	log.Info().Str("source", sourceSpec.VersionString()).Strs("destinations", destinationStrings).Msg("Start fetching resources")
 	fmt.Printf("Starting sync for: %s -> %s\n", sourceSpec.VersionString(), destinationStrings)
-	g.Go(func() error {
-		defer close(resources)
-		if err := sourceClient.Sync(gctx, resources); err != nil {
-			if isUnknownConcurrencyFieldError(err) {
-				return fmt.Errorf("unsupported version of source %s. Please update to the latest version from https://cloudquery.io/docs/plugins/sources", sourceSpec.VersionString())
-			}
-			return fmt.Errorf("failed to sync source %s: %w", sourceSpec.VersionString(), err)
-		}
-		return nil
-	})
-
-	destSubscriptions := make([]chan []byte, len(destinationsSpecs))
-	for i := range destSubscriptions {
-		destSubscriptions[i] = make(chan []byte)
-	}
-	bar := progressbar.NewOptions(-1,
-		progressbar.OptionSetDescription("Syncing resources..."),
-		progressbar.OptionSetItsString("resources"),
-		progressbar.OptionShowIts(),
-		progressbar.OptionSetElapsedTime(true),
-		progressbar.OptionShowCount(),
-		progressbar.OptionClearOnFinish(),
-	)
+
+	bar := newSyncProgressBar(parallel)
 	failedWrites := uint64(0)
 	totalResources := uint64(0)
+	// destinations with an on-error policy other than fail don't stop the sync when writing to them fails. Once a
+	// destination failed it doesn't receive any more resources, in this batch or the following ones. The resources it
+	// didn't accept, and the last ones it accepted but may not have written, are counted as failed, and written to
+	// dead-letter files with the dead_letter policy.
+	failedDestinations := make([]error, len(destinationsSpecs))
+	deadLetters := make([]*deadLetterQueue, len(destinationsSpecs))
 	for i, destination := range destinationsSpecs {
-		i := i
-		destination := destination
-		g.Go(func() error {
-			var destFailedWrites uint64
-			var err error
-			if err = destClients[i].Write2(gctx, sourceSpec, tables, syncTime, destSubscriptions[i]); err != nil {
-				return fmt.Errorf("failed to write for %s -> %s: %w", sourceSpec.VersionString(), destination.VersionString(), err)
+		var newWriter func() (*deadLetterWriter, error)
+		switch onError.policy(destination.Name) {
+		case onErrorFail:
+			continue
+		case onErrorDeadLetter:
+			dir := filepath.Join(onError.deadLetterDir, sourceSpec.Name, destination.Name)
+			newWriter = func() (*deadLetterWriter, error) {
+				return newDeadLetterWriter(dir, onError.deadLetterFormat, tables)
+			}
+		}
+		deadLetters[i] = newDeadLetterQueue(destination.BatchSize, newWriter)
+	}
+	defer func() {
+		for i, q := range deadLetters {
+			if q == nil || q.writer == nil {
+				continue
 			}
-			// call Close on destination client using the outer context, so that it happens even if writes get cancelled
-			if err := destClients[i].Close(ctx); err != nil {
-				return fmt.Errorf("failed to close destination client for %s -> %s: %w", sourceSpec.VersionString(), destination.VersionString(), err)
+			w := q.writer
+			if err := w.close(); err != nil {
+				log.Error().Err(err).Str("destination", destinationsSpecs[i].Name).Msg("Failed to write dead-letter resources")
+				fmt.Println("failed to write dead-letter resources:", err)
+				continue
+			}
+			log.Warn().Str("destination", destinationsSpecs[i].Name).Str("dir", w.dir).Uint64("resources", w.resources).Msg("Wrote dead-letter resources")
+			fmt.Printf("Wrote %d resources that %s failed to accept to %s\n", w.resources, destinationsSpecs[i].VersionString(), w.dir)
+		}
+	}()
+	syncBatch := func(batch schema.Tables) error {
+		resources := make(chan []byte)
+		g, gctx := errgroup.WithContext(sourceCtx)
+		g.Go(func() error {
+			defer close(resources)
+			if err := sourceClient.Sync(gctx, resources); err != nil {
+				if isUnknownConcurrencyFieldError(err) {
+					return fmt.Errorf("unsupported version of source %s. Please update to the latest version from https://cloudquery.io/docs/plugins/sources", sourceSpec.VersionString())
+				}
+				return fmt.Errorf("failed to sync source %s: %w", sourceSpec.VersionString(), err)
 			}
-			atomic.AddUint64(&failedWrites, destFailedWrites)
 			return nil
 		})
-	}
 
-	g.Go(func() error {
-		t := time.NewTicker(1 * time.Second)
-		defer func() {
-			for i := range destSubscriptions {
-				close(destSubscriptions[i])
+		destSubscriptions := make([]chan []byte, len(destinationsSpecs))
+		// destFailed is closed when writing to a destination that doesn't stop the sync on errors failed
+		destFailed := make([]chan struct{}, len(destinationsSpecs))
+		for i := range destSubscriptions {
+			destFailed[i] = make(chan struct{})
+			if failedDestinations[i] != nil {
+				// the destination failed in a previous batch, so it isn't written to again
+				close(destFailed[i])
+				continue
 			}
-			t.Stop()
-		}()
-		for {
-			select {
-			case resource, ok := <-resources:
-				if !ok {
-					return nil
+			destSubscriptions[i] = make(chan []byte)
+			if deadLetters[i] != nil {
+				// resources of previous batches were written by the destination
+				deadLetters[i].reset()
+			}
+		}
+		for i, destination := range destinationsSpecs {
+			if failedDestinations[i] != nil {
+				continue
+			}
+			i := i
+			destination := destination
+			g.Go(func() error {
+				if err := destClients[i].Write2(gctx, sourceSpec, batch, syncTime, destSubscriptions[i]); err != nil {
+					err = fmt.Errorf("failed to write for %s -> %s: %w", sourceSpec.VersionString(), destination.VersionString(), err)
+					policy := onError.policy(destination.Name)
+					if policy == onErrorFail {
+						return err
+					}
+					log.Error().Err(err).Str("on_error", policy).Msg("Write failed, continuing with the other destinations")
+					failedDestinations[i] = err
+					close(destFailed[i])
 				}
-				totalResources++
-				_ = bar.Add(1)
+				return nil
+			})
+		}
+
+		g.Go(func() error {
+			t := time.NewTicker(1 * time.Second)
+			lastProgress := time.Now()
+			defer func() {
 				for i := range destSubscriptions {
-					select {
-					case <-gctx.Done():
-						return gctx.Err()
-					case destSubscriptions[i] <- resource:
+					if destSubscriptions[i] != nil {
+						close(destSubscriptions[i])
 					}
 				}
-			case <-t.C:
-				_ = bar.Add(0)
-			case <-gctx.Done():
-				return nil
+				t.Stop()
+			}()
+			for {
+				select {
+				case resource, ok := <-resources:
+					if !ok {
+						return nil
+					}
+					totalResources++
+					_ = bar.Add(1)
+					exporter.resourceReceived(sourceSpec, destinationsSpecs, resource)
+					summary.resourceSynced(resource)
+					for i := range destSubscriptions {
+						sendStart := time.Now()
+						select {
+						case <-gctx.Done():
+							return gctx.Err()
+						case destSubscriptions[i] <- resource:
+							exporter.resourceSent(sourceSpec, destinationsSpecs[i], time.Since(sendStart))
+							if deadLetters[i] != nil {
+								deadLetters[i].sent(resource)
+							}
+						case <-destFailed[i]:
+							exporter.resourceFailed(sourceSpec, destinationsSpecs[i])
+							if err := deadLetters[i].failed(resource); err != nil {
+								return err
+							}
+						}
+					}
+				case <-t.C:
+					_ = bar.Add(0)
+					if parallel && time.Since(lastProgress) >= parallelProgressInterval {
+						printSyncProgress(sourceSpec, totalResources, syncStart)
+						lastProgress = time.Now()
+					}
+				case <-gctx.Done():
+					return nil
+				}
+			}
+		})
+		err := g.Wait()
+		for i := range destinationsSpecs {
+			if failedDestinations[i] == nil {
+				continue
+			}
+			// the destination may have failed after it accepted the last resource
+			if err := deadLetters[i].failed(nil); err != nil {
+				return err
 			}
 		}
-	})
+		return err
+	}
 
-	if err := g.Wait(); err != nil {
-		exitReason = "sync failed"
-		if canceled {
-			exitReason = "sync canceled"
+	for _, batch := range batches {
+		if checkpoint != nil {
+			if err := sourceClient.Init(sourceCtx, batchSourceSpec(sourceSpec, batch)); err != nil {
+				exitReason = "failed to init"
+				return fmt.Errorf("failed to init source %s for tables %v: %w", sourceSpec.VersionString(), batch.TableNames(), err)
+			}
+		}
+		if err := syncBatch(batch); err != nil {
+			pollCancel()
+			exitReason = "sync failed"
+			if canceled {
+				exitReason = "sync canceled"
+			}
+			_ = bar.Finish()
+			if checkpoint != nil {
+				fmt.Printf("Sync of %s was interrupted. Run sync with --resume to continue from the last checkpoint.\n", sourceSpec.VersionString())
+			}
+			return err
+		}
+		if checkpoint != nil {
+			if err := checkpoint.complete(batch); err != nil {
+				exitReason = "failed to save checkpoint"
+				_ = bar.Finish()
+				return err
+			}
+			log.Info().Str("source", sourceSpec.VersionString()).Strs("tables", batch.TableNames()).Msg("Checkpoint saved")
+		}
+	}
+	pollCancel()
+
+	for i, destination := range destinationsSpecs {
+		if failedDestinations[i] != nil {
+			// closing finalizes the output of some destinations, e.g. markers of complete data or exports, so a
+			// destination that failed to write isn't closed
+			log.Warn().Str("destination", destination.Name).Msg("Not closing destination that failed to write")
+			continue
+		}
+		// call Close on destination client using the outer context, so that it happens even if writes get cancelled
+		if err := destClients[i].Close(ctx); err != nil {
+			err = fmt.Errorf("failed to close destination client for %s -> %s: %w", sourceSpec.VersionString(), destination.VersionString(), err)
+			if onError.policy(destination.Name) != onErrorFail {
+				log.Error().Err(err).Msg("Failed to close destination client")
+				continue
+			}
+			exitReason = "sync failed"
+			_ = bar.Finish()
+			return err
 		}
-		_ = bar.Finish()
-		return err
 	}
 	_ = bar.Finish()
-	syncTimeTook := time.Since(syncTime)
+	syncTimeTook := time.Since(syncStart)
 
+	for i, destination := range destinationsSpecs {
+		if failedDestinations[i] != nil {
+			failedWrites += deadLetters[i].failedResources
+			summary.FailedDestinations = append(summary.FailedDestinations, destination.VersionString())
+			fmt.Printf("Writing to %s failed, %d resources may not have been written and the destination was not finalized: %v\n", destination.VersionString(), deadLetters[i].failedResources, failedDestinations[i])
+			continue
+		}
+		// destinations that didn't fail the write can still report resources they failed to write
+		destMetrics, err := destClients[i].GetMetrics(sourceCtx)
+		if err != nil {
+			log.Warn().Err(err).Str("destination", destination.Name).Msg("Failed to get destination metrics")
+			continue
+		}
+		failedWrites += destMetrics.Errors
+	}
+	summary.FailedWrites = failedWrites
+
+	exporter.update(sourceCtx, sourceSpec, sourceClient, destinationsSpecs, destClients)
 	metrics, err = sourceClient.GetMetrics(sourceCtx)
 	if err != nil {
 		exitReason = "failed to get metrics"
 		return fmt.Errorf("failed to get metrics for source %s: %w", sourceSpec.VersionString(), err)
 	}
 
+	if checkpoint != nil {
+		if err := checkpoint.remove(); err != nil {
+			log.Warn().Err(err).Msg("Failed to remove checkpoint")
+		}
+	}
+
 	exitReason = "success"
-	fmt.Printf("Sync completed successfully. Resources: %d, Errors: %d, Panics: %d, Time: %s\n", metrics.TotalResources(), metrics.TotalErrors(), metrics.TotalPanics(), syncTimeTook.Truncate(time.Second).String())
+	fmt.Printf("Sync completed successfully. Resources: %d, Errors: %d, Panics: %d, Failed writes: %d, Time: %s\n", metrics.TotalResources(), metrics.TotalErrors(), metrics.TotalPanics(), failedWrites, syncTimeTook.Truncate(time.Second).String())
 	return nil
 }
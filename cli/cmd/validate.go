@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudquery/cloudquery/cli/internal/enum"
+	destination "github.com/cloudquery/plugin-sdk/clients/destination/v0"
+	discovery "github.com/cloudquery/plugin-sdk/clients/discovery/v0"
+	source "github.com/cloudquery/plugin-sdk/clients/source/v1"
+	"github.com/cloudquery/plugin-sdk/registry"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	validateShort = "Validate source and destination specs without syncing"
+	validateLong  = `Validate source and destination specs without syncing
+
+Specs are loaded and checked without downloading or starting any plugin: every source must
+reference existing destinations and every source and destination spec must pass validation.
+Use --init-plugins to also start every plugin and initialize it with its spec, so that errors in
+the plugin-specific configuration (the nested "spec" section), such as missing or unknown fields,
+are reported too. Initializing a plugin may connect to the services it's configured for, such as
+the database of a destination. No resources are synced.`
+	validateExample = `# Validate specs in a directory
+cloudquery validate ./directory
+# Validate specs from directories and files and print the result as JSON
+cloudquery validate ./directory ./aws.yml ./pg.yml --format json
+# Also start the plugins and validate their nested spec
+cloudquery validate ./directory --init-plugins
+`
+)
+
+type validationError struct {
+	Kind    string `json:"kind,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e validationError) String() string {
+	if e.Kind == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s %s: %s", e.Kind, e.Name, e.Message)
+}
+
+type validationResult struct {
+	Valid        bool              `json:"valid"`
+	Sources      int               `json:"sources"`
+	Destinations int               `json:"destinations"`
+	Errors       []validationError `json:"errors"`
+	Warnings     []validationError `json:"warnings,omitempty"`
+}
+
+func NewCmdValidate() *cobra.Command {
+	format := enum.NewEnum([]string{"text", "json"}, "text")
+	cmd := &cobra.Command{
+		Use:     "validate [files or directories]",
+		Short:   validateShort,
+		Long:    validateLong,
+		Example: validateExample,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return validate(cmd, args, format.String())
+		},
+	}
+	cmd.Flags().Var(format, "format", "Output format. One of: text, json")
+	cmd.Flags().Bool("init-plugins", false, "Also download, start and initialize every plugin with its spec to validate the nested spec. Plugins may connect to the services they're configured for")
+	return cmd
+}
+
+func validate(cmd *cobra.Command, args []string, format string) error {
+	cqDir, err := cmd.Flags().GetString("cq-dir")
+	if err != nil {
+		return err
+	}
+	initPlugins, err := cmd.Flags().GetBool("init-plugins")
+	if err != nil {
+		return err
+	}
+
+	log.Info().Strs("args", args).Bool("init_plugins", initPlugins).Msg("Validating spec(s)")
+	result, specReader := validateSpecs(args)
+	if result.Valid && initPlugins {
+		validatePlugins(cmd.Context(), cqDir, specReader, &result)
+		result.Valid = len(result.Errors) == 0
+	}
+
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal validation result: %w", err)
+		}
+		fmt.Fprintln(os.Stdout, string(b))
+	default:
+		fmt.Printf("Validating spec(s) from %s\n", strings.Join(args, ", "))
+		for _, w := range result.Warnings {
+			fmt.Println("Warning:", w.String())
+		}
+		for _, e := range result.Errors {
+			fmt.Println("Error:", e.String())
+		}
+		if result.Valid {
+			fmt.Printf("Spec(s) are valid. Sources: %d, Destinations: %d\n", result.Sources, result.Destinations)
+		}
+	}
+
+	if !result.Valid {
+		log.Error().Int("errors", len(result.Errors)).Msg("Spec validation failed")
+		return fmt.Errorf("spec validation failed with %d error(s)", len(result.Errors))
+	}
+	log.Info().Int("sources", result.Sources).Int("destinations", result.Destinations).Msg("Spec validation succeeded")
+	return nil
+}
+
+// validateSpecs loads the specs from the given paths and collects every error found,
+// instead of stopping at the first one. The spec reader already sets the defaults of every spec, validates it and
+// resolves the destinations of the sources, so only the checks it doesn't do are left here.
+func validateSpecs(paths []string) (validationResult, *specs.SpecReader) {
+	result := validationResult{Errors: make([]validationError, 0)}
+	specReader, _, err := readSpecs(paths)
+	if err != nil {
+		result.Errors = append(result.Errors, validationError{
+			Message: fmt.Sprintf("failed to load spec(s) from %s: %v", strings.Join(paths, ", "), err),
+		})
+		// The spec reader stops at the first error, so load every file on its own to find errors in the other files too.
+		files, filesErr := specFiles(paths)
+		if filesErr != nil || len(files) < 2 {
+			return result, nil
+		}
+		for _, file := range files {
+			if _, _, fileErr := readSpecs([]string{file}); fileErr != nil && fileErr.Error() != err.Error() && !isCrossFileSpecError(fileErr) {
+				result.Errors = append(result.Errors, validationError{Kind: "file", Name: file, Message: fileErr.Error()})
+			}
+		}
+		return result, nil
+	}
+	result.Sources = len(specReader.Sources)
+	result.Destinations = len(specReader.Destinations)
+
+	referenced := make(map[string]bool, len(specReader.Destinations))
+	for _, sourceSpec := range specReader.Sources {
+		addErr := func(format string, a ...any) {
+			result.Errors = append(result.Errors, validationError{Kind: "source", Name: sourceSpec.Name, Message: fmt.Sprintf(format, a...)})
+		}
+		if sourceSpec.TableConcurrency != 0 || sourceSpec.ResourceConcurrency != 0 {
+			log.Warn().Str("source", sourceSpec.Name).Msg("table_concurrency and resource_concurrency are deprecated, use concurrency instead")
+		}
+		seen := make(map[string]bool, len(sourceSpec.Destinations))
+		for _, destination := range sourceSpec.Destinations {
+			if seen[destination] {
+				addErr("destination %s is listed more than once", destination)
+				continue
+			}
+			seen[destination] = true
+			referenced[destination] = true
+		}
+	}
+
+	for _, destinationSpec := range specReader.Destinations {
+		if !referenced[destinationSpec.Name] {
+			log.Warn().Str("destination", destinationSpec.Name).Msg("destination is not referenced by any source")
+		}
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result, specReader
+}
+
+// validatePlugins starts every plugin and initializes it with its spec, the same way sync does, so that the plugins
+// validate their nested spec. Nothing is synced or written: the plugins are terminated right after initialization.
+// The errors and warnings are added to the result.
+func validatePlugins(ctx context.Context, cqDir string, specReader *specs.SpecReader, result *validationResult) {
+	for _, sourceSpec := range specReader.Sources {
+		supported, err := initSourcePlugin(ctx, cqDir, *sourceSpec)
+		if err != nil {
+			result.Errors = append(result.Errors, validationError{Kind: "source", Name: sourceSpec.Name, Message: pluginSpecErrorMessage(err)})
+			continue
+		}
+		if !supported {
+			result.Warnings = append(result.Warnings, validationError{Kind: "source", Name: sourceSpec.Name, Message: fmt.Sprintf("spec validation isn't supported by %s, skipping", sourceSpec.VersionString())})
+		}
+	}
+	for _, destinationSpec := range specReader.Destinations {
+		if err := initDestinationPlugin(ctx, cqDir, *destinationSpec); err != nil {
+			result.Errors = append(result.Errors, validationError{Kind: "destination", Name: destinationSpec.Name, Message: pluginSpecErrorMessage(err)})
+		}
+	}
+}
+
+// initSourcePlugin returns false if the source plugin is too old to be initialized without syncing
+func initSourcePlugin(ctx context.Context, cqDir string, sourceSpec specs.Source) (bool, error) {
+	discoveryClient, err := discovery.NewClient(ctx, sourceSpec.Registry, registry.PluginTypeSource, sourceSpec.Path, sourceSpec.Version, discovery.WithDirectory(cqDir))
+	if err != nil {
+		return false, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	versions, err := discoveryClient.GetVersions(ctx)
+	if terminateErr := discoveryClient.Terminate(); terminateErr != nil {
+		log.Error().Err(terminateErr).Msg("failed to terminate discovery client")
+	}
+	if err != nil || slices.Index(versions, "v1") == -1 {
+		// older source plugins only receive their spec when syncing, so their spec can't be validated on its own
+		log.Warn().Str("source", sourceSpec.VersionString()).Msg("Source plugin doesn't support spec validation, skipping")
+		return false, nil
+	}
+
+	opts := []source.ClientOption{
+		source.WithLogger(log.Logger),
+		source.WithDirectory(cqDir),
+	}
+	if disableSentry {
+		opts = append(opts, source.WithNoSentry())
+	}
+	sourceClient, err := source.NewClient(ctx, sourceSpec.Registry, sourceSpec.Path, sourceSpec.Version, opts...)
+	if err != nil {
+		return false, fmt.Errorf("failed to get source plugin client: %w", err)
+	}
+	defer func() {
+		if err := sourceClient.Terminate(); err != nil {
+			log.Error().Err(err).Msg("Failed to terminate source client")
+		}
+	}()
+	return true, sourceClient.Init(ctx, sourceSpec)
+}
+
+func initDestinationPlugin(ctx context.Context, cqDir string, destinationSpec specs.Destination) error {
+	opts := []destination.ClientOption{
+		destination.WithLogger(log.Logger),
+		destination.WithDirectory(cqDir),
+	}
+	if disableSentry {
+		opts = append(opts, destination.WithNoSentry())
+	}
+	destClient, err := destination.NewClient(ctx, destinationSpec.Registry, destinationSpec.Path, destinationSpec.Version, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create destination plugin client: %w", err)
+	}
+	// the destination is terminated without being closed, so that it doesn't finalize any output
+	defer func() {
+		if err := destClient.Terminate(); err != nil {
+			log.Error().Err(err).Msg("Failed to terminate destination client")
+		}
+	}()
+	return destClient.Initialize(ctx, destinationSpec)
+}
+
+// pluginSpecErrorMessage returns the message of an error returned by a plugin initialized with its spec, reporting
+// unknown fields the same way for all plugins.
+func pluginSpecErrorMessage(err error) string {
+	msg := err.Error()
+	if i := strings.Index(msg, unknownFieldErrorPrefix); i != -1 {
+		field := msg[i+len(unknownFieldErrorPrefix):]
+		if end := strings.IndexAny(field, " \n"); end != -1 {
+			field = field[:end]
+		}
+		return "unknown field " + field + " in spec"
+	}
+	return msg
+}
+
+// specFiles expands the given paths into the list of spec files, the same way the spec reader does.
+func specFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		fileInfo, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !fileInfo.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() && !strings.HasPrefix(name, ".") && (strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")) {
+				files = append(files, filepath.Join(p, name))
+			}
+		}
+	}
+	return files, nil
+}
+
+// isCrossFileSpecError returns true for spec reader errors that only make sense when all the specs are loaded together,
+// e.g. a file that holds only a source and no destination.
+func isCrossFileSpecError(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, "expecting at least one") ||
+		strings.Contains(msg, "references unknown destination") ||
+		strings.Contains(msg, "is used by multiple sources")
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	configs := []struct {
+		name        string
+		config      []string
+		initPlugins bool
+		err         string
+	}{
+		{
+			name:   "should succeed for valid config",
+			config: []string{"sync-success.yml"},
+		},
+		{
+			name:        "should succeed with initialized plugins for valid config",
+			config:      []string{"sync-success.yml"},
+			initPlugins: true,
+		},
+		{
+			name:        "should fail with initialized plugins for unknown destination",
+			config:      []string{"validate-unknown-destination.yml"},
+			initPlugins: true,
+			err:         "spec validation failed with 1 error(s)",
+		},
+		{
+			name:   "should succeed for multiple sources and destinations",
+			config: []string{"multiple-sources-destinations.yml"},
+		},
+		{
+			name:   "should fail with missing path error when path is missing",
+			config: []string{"sync-missing-path-error.yml"},
+			err:    "spec validation failed with 1 error(s)",
+		},
+		{
+			name:   "should fail for unknown destination",
+			config: []string{"validate-unknown-destination.yml"},
+			err:    "spec validation failed with 1 error(s)",
+		},
+		{
+			name:   "should report errors from every file",
+			config: []string{"sync-missing-path-error.yml", "validate-invalid-version.yml"},
+			err:    "spec validation failed with 2 error(s)",
+		},
+	}
+
+	_, filename, _, _ := runtime.Caller(0)
+	currentDir := path.Dir(filename)
+	for _, tc := range configs {
+		t.Run(tc.name, func(t *testing.T) {
+			defer CloseLogFile()
+			tmpDir := t.TempDir()
+			args := []string{"validate"}
+			for _, config := range tc.config {
+				args = append(args, path.Join(currentDir, "testdata", config))
+			}
+			args = append(args, "--cq-dir", tmpDir, "--log-file-name", path.Join(tmpDir, "cloudquery.log"))
+			if tc.initPlugins {
+				args = append(args, "--init-plugins")
+			}
+			cmd := NewCmdRoot()
+			cmd.SetArgs(args)
+			err := cmd.Execute()
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestValidateSpecs(t *testing.T) {
+	_, filename, _, _ := runtime.Caller(0)
+	currentDir := path.Dir(filename)
+
+	result, _ := validateSpecs([]string{path.Join(currentDir, "testdata", "validate-unknown-destination.yml")})
+	require.False(t, result.Valid)
+	require.Len(t, result.Errors, 1)
+	require.Contains(t, result.Errors[0].Message, "source test references unknown destination tset")
+
+	result, specReader := validateSpecs([]string{path.Join(currentDir, "testdata", "multiple-sources-destinations.yml")})
+	require.True(t, result.Valid)
+	require.NotNil(t, specReader)
+	require.Equal(t, 2, result.Sources)
+	require.Equal(t, 2, result.Destinations)
+}
+
+func TestPluginSpecErrorMessage(t *testing.T) {
+	err := errors.New(`rpc error: ` + unknownFieldErrorPrefix + `"connection_strng"`)
+	require.Equal(t, `unknown field "connection_strng" in spec`, pluginSpecErrorMessage(err))
+
+	err = errors.New("failed to initialize client: connection_string is required")
+	require.Equal(t, err.Error(), pluginSpecErrorMessage(err))
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strings"
@@ -8,6 +9,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/cloudquery/cloudquery/plugins/destination/internal/manifest"
 	"github.com/cloudquery/filetypes"
 	"github.com/cloudquery/plugin-sdk/plugins/destination"
 	"github.com/cloudquery/plugin-sdk/specs"
@@ -21,6 +23,8 @@ type Client struct {
 	pluginSpec Spec
 
 	storageClient *azblob.Client
+	// manifests is nil unless manifests or _SUCCESS markers are written
+	manifests *manifest.Manifests
 
 	*filetypes.Client
 }
@@ -47,6 +51,9 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 		return nil, fmt.Errorf("failed to create filetypes client: %w", err)
 	}
 	c.Client = filetypesClient
+	if c.pluginSpec.Manifest || c.pluginSpec.SuccessMarker {
+		c.manifests = manifest.New(c.pluginSpec.Path, string(c.pluginSpec.Format))
+	}
 	cred, err := azidentity.NewDefaultAzureCredential(nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Azure credential: %w", err)
@@ -64,6 +71,19 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 	return c, nil
 }
 
-func (*Client) Close(ctx context.Context) error {
+// Close writes the manifests and _SUCCESS markers of the blobs written during the sync
+func (c *Client) Close(ctx context.Context) error {
+	if c.manifests == nil {
+		return nil
+	}
+	files, err := c.manifests.Files(c.pluginSpec.Manifest, c.pluginSpec.SuccessMarker)
+	if err != nil {
+		return fmt.Errorf("failed to create manifests: %w", err)
+	}
+	for _, f := range files {
+		if _, err := c.storageClient.UploadStream(ctx, c.pluginSpec.Container, f.Key, bytes.NewReader(f.Body), nil); err != nil {
+			return fmt.Errorf("failed to write %s to Azure: %w", f.Key, err)
+		}
+	}
 	return nil
 }
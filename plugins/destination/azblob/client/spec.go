@@ -2,6 +2,7 @@ package client
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/cloudquery/filetypes"
 )
@@ -12,9 +13,21 @@ type Spec struct {
 	Path           string `json:"path,omitempty"`
 	NoRotate       bool   `json:"no_rotate,omitempty"`
 	*filetypes.FileSpec
+
+	Manifest      bool `json:"manifest,omitempty"`
+	SuccessMarker bool `json:"success_marker,omitempty"`
 }
 
-func (*Spec) SetDefaults() {}
+func (s *Spec) SetDefaults() {
+	if !strings.Contains(s.Path, PathVarTable) {
+		// to keep the object names of earlier versions, default to given path plus /{{TABLE}}.[format].{{UUID}} if
+		// no {{TABLE}} value is found in the path string
+		s.Path += fmt.Sprintf("/%s.%s", PathVarTable, s.Format)
+		if !s.NoRotate {
+			s.Path += "." + PathVarUUID
+		}
+	}
+}
 
 func (s *Spec) Validate() error {
 	if s.StorageAccount == "" {
@@ -26,6 +39,9 @@ func (s *Spec) Validate() error {
 	if s.Path == "" {
 		return fmt.Errorf("path is required")
 	}
+	if s.NoRotate && strings.Contains(s.Path, PathVarUUID) {
+		return fmt.Errorf("path should not contain %s when no_rotate = true", PathVarUUID)
+	}
 	if s.Format == "" {
 		return fmt.Errorf("format is required")
 	}
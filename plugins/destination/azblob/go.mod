@@ -5,12 +5,15 @@ go 1.19
 require (
 	github.com/Azure/azure-sdk-for-go/sdk/azidentity v1.2.2
 	github.com/Azure/azure-sdk-for-go/sdk/storage/azblob v1.0.0
+	github.com/cloudquery/cloudquery/plugins/destination/internal/manifest v0.0.0-00010101000000-000000000000
 	github.com/cloudquery/filetypes v1.6.2
 	github.com/cloudquery/plugin-sdk v1.44.2
 	github.com/google/uuid v1.3.0
 	github.com/rs/zerolog v1.29.0
 )
 
+replace github.com/cloudquery/cloudquery/plugins/destination/internal/manifest => ../internal/manifest
+
 replace github.com/apache/arrow/go/v12 => github.com/cloudquery/arrow/go/v12 v12.0.0-20230317130341-c648117570af
 
 require (
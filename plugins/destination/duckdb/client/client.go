@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/cloudquery/plugin-sdk/plugins/destination"
+	"github.com/cloudquery/plugin-sdk/schema"
 	"github.com/cloudquery/plugin-sdk/specs"
 	"github.com/rs/zerolog"
 
@@ -16,10 +17,14 @@ import (
 type Client struct {
 	destination.UnimplementedManagedWriter
 	destination.DefaultReverseTransformer
-	db      *sql.DB
-	logger  zerolog.Logger
-	spec    specs.Destination
-	metrics destination.Metrics
+	db         *sql.DB
+	logger     zerolog.Logger
+	spec       specs.Destination
+	duckdbSpec Spec
+	metrics    destination.Metrics
+
+	// syncedTables are the tables exported when the client is closed
+	syncedTables schema.Tables
 }
 
 func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (destination.Client, error) {
@@ -33,6 +38,10 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 		return nil, fmt.Errorf("failed to unmarshal duckdb spec: %w", err)
 	}
 	duckdbSpec.SetDefaults()
+	if err := duckdbSpec.Validate(); err != nil {
+		return nil, err
+	}
+	c.duckdbSpec = duckdbSpec
 
 	db, err := sql.Open("duckdb", duckdbSpec.ConnectionString)
 	if err != nil {
@@ -43,11 +52,17 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 }
 
 func (c *Client) Close(ctx context.Context) error {
-	var err error
 	if c.db == nil {
 		return fmt.Errorf("client already closed or not initialized")
 	}
-	err = c.db.Close()
+	var exportErr error
+	if c.duckdbSpec.ExportPath != "" {
+		exportErr = c.export(ctx)
+	}
+	err := c.db.Close()
 	c.db = nil
+	if exportErr != nil {
+		return exportErr
+	}
 	return err
 }
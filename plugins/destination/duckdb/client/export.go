@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	exportViewsFileName = "views.duckdb"
+
+	loadParquet = `INSTALL 'parquet'; LOAD 'parquet';`
+)
+
+// export copies every synced table to a file in the export directory, and creates a database with a view over
+// every file if export_views is set
+func (c *Client) export(ctx context.Context) error {
+	if len(c.syncedTables) == 0 {
+		return nil
+	}
+	dir, err := filepath.Abs(c.duckdbSpec.ExportPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create export directory %s: %w", dir, err)
+	}
+	if c.duckdbSpec.ExportFormat == ExportFormatParquet {
+		if _, err := c.db.ExecContext(ctx, loadParquet); err != nil {
+			return fmt.Errorf("failed to load the parquet extension: %w", err)
+		}
+	}
+
+	files := make(map[string]string)
+	for _, table := range c.syncedTables.FlattenTables() {
+		info, err := c.getTableInfo(table.Name)
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			continue
+		}
+		file := filepath.Join(dir, table.Name+"."+string(c.duckdbSpec.ExportFormat))
+		query := copyToSQL(table.Name, file, c.duckdbSpec.ExportFormat)
+		if _, err := c.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to export table %s with '%s': %w", table.Name, query, err)
+		}
+		files[table.Name] = file
+		c.logger.Info().Str("table", table.Name).Str("file", file).Msg("Exported table")
+	}
+
+	if c.duckdbSpec.ExportViews {
+		return c.exportViews(ctx, filepath.Join(dir, exportViewsFileName), files)
+	}
+	return nil
+}
+
+// exportViews creates a database with a view over the exported file of every table. The views read the files by
+// their absolute path.
+func (c *Client) exportViews(ctx context.Context, path string, files map[string]string) error {
+	db, err := sql.Open("duckdb", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if c.duckdbSpec.ExportFormat == ExportFormatParquet {
+		if _, err := db.ExecContext(ctx, loadParquet); err != nil {
+			return fmt.Errorf("failed to load the parquet extension: %w", err)
+		}
+	}
+	for table, file := range files {
+		query := createViewSQL(table, file, c.duckdbSpec.ExportFormat)
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to create view %s with '%s': %w", table, query, err)
+		}
+	}
+	c.logger.Info().Str("file", path).Int("views", len(files)).Msg("Created views over exported tables")
+	return nil
+}
+
+func copyToSQL(table string, file string, format ExportFormat) string {
+	var sb strings.Builder
+	sb.WriteString(`copy "` + table + `" to `)
+	sb.WriteString(quoteString(file))
+	if format == ExportFormatCSV {
+		sb.WriteString(" (format csv, header)")
+	} else {
+		sb.WriteString(" (format parquet)")
+	}
+	return sb.String()
+}
+
+func createViewSQL(table string, file string, format ExportFormat) string {
+	var sb strings.Builder
+	sb.WriteString(`create or replace view "` + table + `" as select * from `)
+	if format == ExportFormatCSV {
+		sb.WriteString("read_csv_auto(" + quoteString(file) + ", header=true)")
+	} else {
+		sb.WriteString("read_parquet(" + quoteString(file) + ")")
+	}
+	return sb.String()
+}
+
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudquery/plugin-sdk/plugins/destination"
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/rs/zerolog"
+)
+
+func TestExportCSV(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "export")
+	spec := &Spec{ExportPath: dir, ExportFormat: ExportFormatCSV, ExportViews: true}
+	client, err := New(ctx, zerolog.Nop(), specs.Destination{WriteMode: specs.WriteModeAppend, BatchSize: 10, Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := schema.Tables{
+		{Name: "test_parent", Columns: schema.ColumnList{
+			{Name: "id", Type: schema.TypeInt},
+			{Name: "tags", Type: schema.TypeStringArray},
+		}, Relations: schema.Tables{
+			{Name: "test_child", Columns: schema.ColumnList{{Name: "name", Type: schema.TypeString}}},
+		}},
+	}
+	if err := client.Migrate(ctx, tables); err != nil {
+		t.Fatal(err)
+	}
+	res := make(chan *destination.ClientResource, 3)
+	res <- &destination.ClientResource{TableName: "test_parent", Data: []any{int64(1), []string{"a", "b"}}}
+	res <- &destination.ClientResource{TableName: "test_parent", Data: []any{int64(2), []string{}}}
+	res <- &destination.ClientResource{TableName: "test_child", Data: []any{"it's"}}
+	close(res)
+	if err := client.Write(ctx, tables, res); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{"test_parent.csv", "test_child.csv", exportViewsFileName} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := sql.Open("duckdb", filepath.Join(dir, exportViewsFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var count int
+	if err := db.QueryRow(`select count(*) from "test_parent"`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 rows in test_parent view, got %d", count)
+	}
+	var name string
+	if err := db.QueryRow(`select "name" from "test_child"`).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "it's" {
+		t.Errorf("expected name it's in test_child view, got %s", name)
+	}
+}
+
+func TestExportSkippedAfterFailedWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "export")
+	spec := &Spec{ExportPath: dir}
+	client, err := New(ctx, zerolog.Nop(), specs.Destination{WriteMode: specs.WriteModeAppend, BatchSize: 10, Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the table isn't migrated, so the write fails
+	tables := schema.Tables{{Name: "test_table", Columns: schema.ColumnList{{Name: "id", Type: schema.TypeInt}}}}
+	res := make(chan *destination.ClientResource, 1)
+	res <- &destination.ClientResource{TableName: "test_table", Data: []any{int64(1)}}
+	close(res)
+	if err := client.Write(ctx, tables, res); err == nil {
+		t.Fatal("expected write to fail")
+	}
+	if err := client.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected no export after a failed write, got %v", err)
+	}
+}
+
+func TestExportSQL(t *testing.T) {
+	cases := []struct {
+		format   ExportFormat
+		wantCopy string
+		wantView string
+	}{
+		{
+			format:   ExportFormatParquet,
+			wantCopy: `copy "test_table" to '/tmp/it''s/test_table.parquet' (format parquet)`,
+			wantView: `create or replace view "test_table" as select * from read_parquet('/tmp/it''s/test_table.parquet')`,
+		},
+		{
+			format:   ExportFormatCSV,
+			wantCopy: `copy "test_table" to '/tmp/it''s/test_table.csv' (format csv, header)`,
+			wantView: `create or replace view "test_table" as select * from read_csv_auto('/tmp/it''s/test_table.csv', header=true)`,
+		},
+	}
+	for _, tc := range cases {
+		file := "/tmp/it's/test_table." + string(tc.format)
+		if got := copyToSQL("test_table", file, tc.format); got != tc.wantCopy {
+			t.Errorf("expected %s, got %s", tc.wantCopy, got)
+		}
+		if got := createViewSQL("test_table", file, tc.format); got != tc.wantView {
+			t.Errorf("expected %s, got %s", tc.wantView, got)
+		}
+	}
+}
+
+func TestSpecValidate(t *testing.T) {
+	cases := []struct {
+		spec    Spec
+		wantErr bool
+	}{
+		{spec: Spec{}},
+		{spec: Spec{ExportPath: "export"}},
+		{spec: Spec{ExportPath: "export", ExportFormat: "json"}, wantErr: true},
+		{spec: Spec{ExportViews: true}, wantErr: true},
+	}
+	for _, tc := range cases {
+		tc.spec.SetDefaults()
+		err := tc.spec.Validate()
+		if tc.wantErr && err == nil {
+			t.Errorf("expected error for %+v", tc.spec)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("unexpected error for %+v: %v", tc.spec, err)
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package client
 
 import (
+	"sync/atomic"
+
 	"github.com/cloudquery/plugin-sdk/plugins/destination"
 )
 
 func (c *Client) Metrics() destination.Metrics {
-	return c.metrics
+	return destination.Metrics{
+		Writes: atomic.LoadUint64(&c.metrics.Writes),
+		Errors: atomic.LoadUint64(&c.metrics.Errors),
+	}
 }
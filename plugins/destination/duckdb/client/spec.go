@@ -1,9 +1,46 @@
 package client
 
+import "fmt"
+
+type ExportFormat string
+
+const (
+	ExportFormatParquet ExportFormat = "parquet"
+	ExportFormatCSV     ExportFormat = "csv"
+)
+
+var exportFormats = []ExportFormat{ExportFormatParquet, ExportFormatCSV}
+
+func (f ExportFormat) Validate() error {
+	for _, format := range exportFormats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v is not a valid option for export format. Options are: %v", f, exportFormats)
+}
+
 type Spec struct {
 	ConnectionString string `json:"connection_string,omitempty"`
+
+	// ExportPath is the directory the synced tables are exported to when the sync closes
+	ExportPath   string       `json:"export_path,omitempty"`
+	ExportFormat ExportFormat `json:"export_format,omitempty"`
+	ExportViews  bool         `json:"export_views,omitempty"`
+}
+
+func (s *Spec) SetDefaults() {
+	if s.ExportFormat == "" {
+		s.ExportFormat = ExportFormatParquet
+	}
 }
 
-func (*Spec) SetDefaults() {
-	// stub for any future defaults
+func (s *Spec) Validate() error {
+	if s.ExportPath == "" {
+		if s.ExportViews {
+			return fmt.Errorf("export_views requires export_path")
+		}
+		return nil
+	}
+	return s.ExportFormat.Validate()
 }
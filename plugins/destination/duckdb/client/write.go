@@ -2,34 +2,224 @@ package client
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	"github.com/cloudquery/plugin-sdk/plugins/destination"
 	"github.com/cloudquery/plugin-sdk/schema"
 	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/marcboeker/go-duckdb"
 )
 
+// Write buffers the resources of every table and writes them in batches of batch_size. The tables are exported
+// on close only if every batch was written.
 func (c *Client) Write(ctx context.Context, tables schema.Tables, res <-chan *destination.ClientResource) error {
-	var sql string
+	batches := make(map[string][][]any)
 	for r := range res {
-		table := tables.Get(r.TableName)
-		if c.spec.WriteMode == specs.WriteModeAppend {
-			sql = c.insertSQL(table, r.Data)
-			expanded := expandData(table, r.Data)
-			if _, err := c.db.Exec(sql, expanded...); err != nil {
-				return fmt.Errorf("failed to execute '%s': %w", sql, err)
-			}
-		} else {
-			err := c.upsert(table, r.Data)
-			if err != nil {
-				return fmt.Errorf("failed to execute upsert: %w", err)
+		batches[r.TableName] = append(batches[r.TableName], r.Data)
+		if len(batches[r.TableName]) >= c.spec.BatchSize {
+			if err := c.writeBatch(ctx, tables.Get(r.TableName), batches[r.TableName]); err != nil {
+				return err
 			}
+			delete(batches, r.TableName)
 		}
 	}
+	for tableName, resources := range batches {
+		if err := c.writeBatch(ctx, tables.Get(tableName), resources); err != nil {
+			return err
+		}
+	}
+	c.syncedTables = tables
 	return nil
 }
 
+func (c *Client) writeBatch(ctx context.Context, table *schema.Table, resources [][]any) error {
+	if err := c.writeTableBatch(ctx, table, resources); err != nil {
+		atomic.AddUint64(&c.metrics.Errors, uint64(len(resources)))
+		return fmt.Errorf("failed to write batch of %d resources to table %s: %w", len(resources), table.Name, err)
+	}
+	atomic.AddUint64(&c.metrics.Writes, uint64(len(resources)))
+	return nil
+}
+
+func (c *Client) writeTableBatch(ctx context.Context, table *schema.Table, resources [][]any) error {
+	if c.spec.WriteMode != specs.WriteModeAppend && len(table.PrimaryKeys()) > 0 {
+		// At time of writing (March 2023), duckdb does not support updating list columns, and fails to insert a
+		// key deleted in the same transaction. As a workaround, we delete the rows in one transaction and insert
+		// them again in another. This makes it non-atomic, unfortunately, but this is unavoidable until support is
+		// added to duckdb itself.
+		// See https://github.com/duckdb/duckdb/blob/c5d9afb97bbf0be12216f3b89ae3131afbbc3156/src/storage/table/list_column_data.cpp#L243-L251
+		resources = lastByPrimaryKey(table, resources)
+		if err := c.deleteByPrimaryKey(ctx, table, resources); err != nil {
+			return err
+		}
+	}
+	if canAppend(table) {
+		return c.appendRows(ctx, table, resources)
+	}
+	return c.insertRows(ctx, table, resources)
+}
+
+// canAppend returns whether the resources of the table can be written with the appender, which supports neither
+// lists nor empty blobs
+func canAppend(table *schema.Table) bool {
+	for _, col := range table.Columns {
+		if isArray(col) || col.Type == schema.TypeByteArray {
+			return false
+		}
+	}
+	return true
+}
+
+// appendRows writes the resources with the appender, which flushes them to the table at once. The appender sets
+// the columns by position, so the values are ordered as the columns of the table in the database, which can have
+// columns removed from the schema.
+func (c *Client) appendRows(ctx context.Context, table *schema.Table, resources [][]any) error {
+	info, err := c.getTableInfo(table.Name)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return fmt.Errorf("table %s not found. make sure to run migrate", table.Name)
+	}
+	indexes := make([]int, len(info.columns))
+	for i, col := range info.columns {
+		indexes[i] = table.Columns.Index(col.name)
+	}
+
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		appender, err := duckdb.NewAppenderFromConn(driverConn.(driver.Conn), "", table.Name)
+		if err != nil {
+			return err
+		}
+		for _, r := range resources {
+			row := make([]driver.Value, len(indexes))
+			for i, index := range indexes {
+				if index != -1 {
+					row[i] = r[index]
+				}
+			}
+			if err := appender.AppendRowArray(row); err != nil {
+				appender.Close()
+				return err
+			}
+		}
+		return appender.Close()
+	})
+}
+
+// insertRows writes the resources with prepared statements in a transaction. Lists are expanded to one parameter
+// per element, so there is a statement for every combination of lengths.
+func (c *Client) insertRows(ctx context.Context, table *schema.Table, resources [][]any) error {
+	return c.withTx(ctx, func(tx *sql.Tx) error {
+		stmts := make(map[string]*sql.Stmt)
+		for _, r := range resources {
+			query := c.insertSQL(table, r)
+			stmt, ok := stmts[query]
+			if !ok {
+				var err error
+				stmt, err = tx.PrepareContext(ctx, query)
+				if err != nil {
+					return fmt.Errorf("failed to prepare '%s': %w", query, err)
+				}
+				defer stmt.Close()
+				stmts[query] = stmt
+			}
+			if _, err := stmt.ExecContext(ctx, expandData(table, r)...); err != nil {
+				return fmt.Errorf("failed to execute '%s': %w", query, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (c *Client) deleteByPrimaryKey(ctx context.Context, table *schema.Table, resources [][]any) error {
+	var sb strings.Builder
+	sb.WriteString("delete from ")
+	sb.WriteString(`"` + table.Name + `"`)
+	sb.WriteString(" where ")
+	pks := table.PrimaryKeys()
+	for i, k := range pks {
+		if i > 0 {
+			sb.WriteString(" and ")
+		}
+		sb.WriteString(`"` + k + `"`)
+		sb.WriteString(" = ")
+		sb.WriteString(fmt.Sprintf("$%d", i+1))
+	}
+	query := sb.String()
+	return c.withTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to prepare '%s': %w", query, err)
+		}
+		defer stmt.Close()
+		pkData := make([]any, len(pks))
+		for _, r := range resources {
+			for i, k := range pks {
+				pkData[i] = r[table.Columns.Index(k)]
+			}
+			if _, err := stmt.ExecContext(ctx, pkData...); err != nil {
+				return fmt.Errorf("failed to execute '%s': %w", query, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (c *Client) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			c.logger.Warn().Err(rollbackErr).Msg("Failed to rollback transaction")
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// lastByPrimaryKey returns the last resource of every primary key, as a batch can have more than one version of a
+// resource
+func lastByPrimaryKey(table *schema.Table, resources [][]any) [][]any {
+	var pkIndexes []int
+	for _, k := range table.PrimaryKeys() {
+		pkIndexes = append(pkIndexes, table.Columns.Index(k))
+	}
+	last := make(map[string]int, len(resources))
+	keys := make([]string, len(resources))
+	for i, r := range resources {
+		pk := make([]any, len(pkIndexes))
+		for j, index := range pkIndexes {
+			pk[j] = r[index]
+		}
+		b, _ := json.Marshal(pk)
+		keys[i] = string(b)
+		last[keys[i]] = i
+	}
+	if len(last) == len(resources) {
+		return resources
+	}
+	deduped := make([][]any, 0, len(last))
+	for i, r := range resources {
+		if last[keys[i]] == i {
+			deduped = append(deduped, r)
+		}
+	}
+	return deduped
+}
+
 func expandData(table *schema.Table, data []any) []any {
 	var expanded []any
 	for i, d := range data {
@@ -49,40 +239,6 @@ func (c *Client) insertSQL(table *schema.Table, data []any) string {
 	return sb.String()
 }
 
-func (c *Client) upsert(table *schema.Table, data []any) error {
-	// At time of writing (March 2023), duckdb does not support updating list columns.
-	// As a workaround, we delete the row and insert it again. This makes it non-atomic, unfortunately,
-	// but this is unavoidable until support is added to duckdb itself.
-	// See https://github.com/duckdb/duckdb/blob/c5d9afb97bbf0be12216f3b89ae3131afbbc3156/src/storage/table/list_column_data.cpp#L243-L251
-	var sb strings.Builder
-	if len(table.PrimaryKeys()) > 0 {
-		sb.WriteString("delete from ")
-		sb.WriteString(`"` + table.Name + `"`)
-		sb.WriteString(" where ")
-		pkData := make([]any, len(table.PrimaryKeys()))
-		for i, k := range table.PrimaryKeys() {
-			col := table.Columns.Get(k)
-			sb.WriteString(`"` + col.Name + `"`)
-			sb.WriteString(" = ")
-			sb.WriteString(fmt.Sprintf("$%d", i+1))
-			pkData[i] = data[table.Columns.Index(k)]
-		}
-		sql := sb.String()
-		if _, err := c.db.Exec(sql, pkData...); err != nil {
-			return fmt.Errorf("failed to execute '%s': %w", sql, err)
-		}
-		sb.Reset()
-	}
-	sb.WriteString("insert into ")
-	c.insertQuery(&sb, table, data)
-	expanded := expandData(table, data)
-	sql := sb.String()
-	if _, err := c.db.Exec(sql, expanded...); err != nil {
-		return fmt.Errorf("failed to execute '%s': %w", sql, err)
-	}
-	return nil
-}
-
 func (*Client) insertQuery(sb *strings.Builder, table *schema.Table, data []any) {
 	sb.WriteString(`"` + table.Name + `"`)
 	sb.WriteString(" (")
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudquery/plugin-sdk/plugins/destination"
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/rs/zerolog"
+)
+
+func TestWriteBatches(t *testing.T) {
+	ctx := context.Background()
+	for _, writeMode := range []specs.WriteMode{specs.WriteModeAppend, specs.WriteModeOverwrite} {
+		t.Run(writeMode.String(), func(t *testing.T) {
+			client, err := New(ctx, zerolog.Nop(), specs.Destination{WriteMode: writeMode, BatchSize: 2, Spec: &Spec{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close(ctx)
+			c := client.(*Client)
+
+			// test_appender is written with the appender, test_insert with prepared statements
+			tables := schema.Tables{
+				{Name: "test_appender", Columns: schema.ColumnList{
+					{Name: "id", Type: schema.TypeUUID, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+					{Name: "name", Type: schema.TypeString},
+					{Name: "created_at", Type: schema.TypeTimestamp},
+					{Name: "count", Type: schema.TypeInt},
+				}},
+				{Name: "test_insert", Columns: schema.ColumnList{
+					{Name: "id", Type: schema.TypeInt, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+					{Name: "tags", Type: schema.TypeStringArray},
+				}},
+			}
+			if err := c.Migrate(ctx, tables); err != nil {
+				t.Fatal(err)
+			}
+
+			now := time.Now().UTC()
+			ids := []string{
+				"b5e5d2a4-1c7a-4a46-8d1a-2c5d1b6f1f01",
+				"b5e5d2a4-1c7a-4a46-8d1a-2c5d1b6f1f02",
+				"b5e5d2a4-1c7a-4a46-8d1a-2c5d1b6f1f03",
+			}
+			resources := []*destination.ClientResource{
+				{TableName: "test_appender", Data: []any{ids[0], "a", now, int64(1)}},
+				{TableName: "test_insert", Data: []any{int64(1), []string{"a"}}},
+				{TableName: "test_appender", Data: []any{ids[1], nil, now, int64(2)}},
+				{TableName: "test_appender", Data: []any{ids[2], "c", nil, int64(3)}},
+				{TableName: "test_appender", Data: []any{ids[2], "d", nil, int64(4)}},
+				{TableName: "test_insert", Data: []any{int64(2), []string{"b", "c"}}},
+				{TableName: "test_insert", Data: []any{int64(1), []string{}}},
+			}
+			res := make(chan *destination.ClientResource, len(resources))
+			for _, r := range resources {
+				res <- r
+			}
+			close(res)
+			if err := c.Write(ctx, tables, res); err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[string]int{"test_appender": 4, "test_insert": 3}
+			if writeMode == specs.WriteModeOverwrite {
+				want = map[string]int{"test_appender": 3, "test_insert": 2}
+			}
+			for tableName, count := range want {
+				var got int
+				if err := c.db.QueryRow(`select count(*) from "` + tableName + `"`).Scan(&got); err != nil {
+					t.Fatal(err)
+				}
+				if got != count {
+					t.Errorf("expected %d rows in %s, got %d", count, tableName, got)
+				}
+			}
+			if writes := c.Metrics().Writes; writes != uint64(len(resources)) {
+				t.Errorf("expected %d writes, got %d", len(resources), writes)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type aliasAction map[string]map[string]any
+
+// readAliasActions returns the actions pointing the read alias of the table from its previous indices to index. The
+// actions are applied atomically, so readers of the alias always see one complete index.
+func readAliasActions(table string, index string) []aliasAction {
+	return []aliasAction{
+		{"remove": {"index": table + "-*", "alias": table, "must_exist": false}},
+		{"add": {"index": index, "alias": table}},
+	}
+}
+
+func (c *Client) updateReadAlias(ctx context.Context, table string, index string) error {
+	b, err := json.Marshal(map[string]any{"actions": readAliasActions(table, index)})
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Indices.UpdateAliases(strings.NewReader(string(b)), c.client.Indices.UpdateAliases.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to update alias %s: %w", table, err)
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		return fmt.Errorf("failed to update alias %s: %s", table, resp.String())
+	}
+	c.logger.Info().Str("alias", table).Str("index", index).Msg("Updated read alias")
+	return nil
+}
+
+type catIndex struct {
+	Index        string `json:"index"`
+	CreationDate string `json:"creation.date"`
+}
+
+// oldIndices returns the indices of the table that are out of retention: all but the newest retain indices, by
+// creation date. The index of the sync is always kept.
+func oldIndices(table string, indices []catIndex, current string, retain int) []string {
+	tableIndices := make([]catIndex, 0, len(indices))
+	for _, index := range indices {
+		// the table names can't contain "-", so that the indices of other tables never match
+		if strings.HasPrefix(index.Index, table+"-") && index.Index != current {
+			tableIndices = append(tableIndices, index)
+		}
+	}
+	sort.Slice(tableIndices, func(i, j int) bool {
+		ci, _ := strconv.ParseInt(tableIndices[i].CreationDate, 10, 64)
+		cj, _ := strconv.ParseInt(tableIndices[j].CreationDate, 10, 64)
+		if ci != cj {
+			return ci > cj
+		}
+		return tableIndices[i].Index > tableIndices[j].Index
+	})
+	if len(tableIndices) < retain {
+		return nil
+	}
+	old := make([]string, 0, len(tableIndices)-retain+1)
+	for _, index := range tableIndices[retain-1:] {
+		old = append(old, index.Index)
+	}
+	return old
+}
+
+func (c *Client) deleteOldIndices(ctx context.Context, table string, current string) error {
+	resp, err := c.client.Cat.Indices(
+		c.client.Cat.Indices.WithContext(ctx),
+		c.client.Cat.Indices.WithIndex(table+"-*"),
+		c.client.Cat.Indices.WithH("index", "creation.date"),
+		c.client.Cat.Indices.WithFormat("json"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to list indices of table %s: %w", table, err)
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		return fmt.Errorf("failed to list indices of table %s: %s", table, resp.String())
+	}
+	var indices []catIndex
+	if err := json.NewDecoder(resp.Body).Decode(&indices); err != nil {
+		return fmt.Errorf("failed to decode indices of table %s: %w", table, err)
+	}
+
+	old := oldIndices(table, indices, current, c.pluginSpec.RetainIndices)
+	if len(old) == 0 {
+		return nil
+	}
+	deleteResp, err := c.client.Indices.Delete(old, c.client.Indices.Delete.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete old indices of table %s: %w", table, err)
+	}
+	defer deleteResp.Body.Close()
+	if deleteResp.IsError() {
+		return fmt.Errorf("failed to delete old indices of table %s: %s", table, deleteResp.String())
+	}
+	c.logger.Info().Str("table", table).Strs("indices", old).Msg("Deleted indices out of retention")
+	return nil
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestOldIndices(t *testing.T) {
+	indices := []catIndex{
+		{Index: "test_table-2023-04-01t10-00-00", CreationDate: "1680343200000"},
+		{Index: "test_table-2023-04-03t10-00-00", CreationDate: "1680516000000"},
+		{Index: "test_table-2023-04-02t10-00-00", CreationDate: "1680429600000"},
+		{Index: "test_table-2023-04-04t10-00-00", CreationDate: "1680602400000"},
+		{Index: "test_table_child-2023-04-01t10-00-00", CreationDate: "1680343200000"},
+	}
+	cases := []struct {
+		retain int
+		want   []string
+	}{
+		{retain: 1, want: []string{"test_table-2023-04-03t10-00-00", "test_table-2023-04-02t10-00-00", "test_table-2023-04-01t10-00-00"}},
+		{retain: 3, want: []string{"test_table-2023-04-01t10-00-00"}},
+		{retain: 4},
+		{retain: 5},
+	}
+	for _, tc := range cases {
+		got := oldIndices("test_table", indices, "test_table-2023-04-04t10-00-00", tc.retain)
+		if !reflect.DeepEqual(tc.want, got) {
+			t.Errorf("retain %d: expected %v, got %v", tc.retain, tc.want, got)
+		}
+	}
+}
+
+func TestReadAliasActions(t *testing.T) {
+	b, err := json.Marshal(readAliasActions("test_table", "test_table-2023-04-01t10-00-00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"remove":{"alias":"test_table","index":"test_table-*","must_exist":false}},{"add":{"alias":"test_table","index":"test_table-2023-04-01t10-00-00"}}]`
+	if string(b) != want {
+		t.Errorf("expected %s, got %s", want, string(b))
+	}
+}
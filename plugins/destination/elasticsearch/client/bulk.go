@@ -0,0 +1,199 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// maxBulkRetries is the number of times the documents of a bulk request that failed with a retryable status are
+// retried
+const maxBulkRetries = 5
+
+type bulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkResponseItem `json:"items"`
+}
+
+type bulkResponseItem struct {
+	ID     string         `json:"_id"`
+	Status int            `json:"status"`
+	Error  *bulkItemError `json:"error,omitempty"`
+}
+
+type bulkItemError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// bulkItemFailure is a document that failed permanently
+type bulkItemFailure struct {
+	id     string
+	status int
+	err    bulkItemError
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseBulkItems returns the positions of the documents to retry and the documents that failed permanently. The
+// items of the response are in the order of the documents of the request.
+func parseBulkItems(resp *bulkResponse, count int) (retry []int, failures []bulkItemFailure, err error) {
+	if len(resp.Items) != count {
+		return nil, nil, fmt.Errorf("bulk response has %d items for %d documents", len(resp.Items), count)
+	}
+	if !resp.Errors {
+		return nil, nil, nil
+	}
+	for i, actions := range resp.Items {
+		for _, item := range actions {
+			if item.Error == nil && item.Status < 300 {
+				continue
+			}
+			if isRetryableStatus(item.Status) {
+				retry = append(retry, i)
+				continue
+			}
+			failures = append(failures, item.failure())
+		}
+	}
+	return retry, failures, nil
+}
+
+func (item bulkResponseItem) failure() bulkItemFailure {
+	failure := bulkItemFailure{id: item.ID, status: item.Status}
+	if item.Error != nil {
+		failure.err = *item.Error
+	}
+	return failure
+}
+
+// bulk indexes the documents, retrying the documents rejected with a retryable status with backoff. Every failed
+// document is counted as an error in the metrics, and an error with the reasons of the failures is returned.
+func (c *Client) bulk(ctx context.Context, table string, index string, docs [][]byte) error {
+	retryBackoff := backoff.NewExponentialBackOff()
+	pending := docs
+	var failures []bulkItemFailure
+	for attempt := 0; ; attempt++ {
+		resp, err := c.bulkRequest(ctx, index, pending)
+		if err != nil {
+			atomic.AddUint64(&c.metrics.Errors, uint64(len(pending)))
+			return err
+		}
+		retry, attemptFailures, err := parseBulkItems(resp, len(pending))
+		if err != nil {
+			atomic.AddUint64(&c.metrics.Errors, uint64(len(pending)))
+			return err
+		}
+		failures = append(failures, attemptFailures...)
+		atomic.AddUint64(&c.metrics.Writes, uint64(len(pending)-len(retry)-len(attemptFailures)))
+		if len(retry) == 0 {
+			break
+		}
+
+		retryDocs := make([][]byte, len(retry))
+		for i, pos := range retry {
+			retryDocs[i] = pending[pos]
+		}
+		pending = retryDocs
+		if attempt == maxBulkRetries {
+			// the documents still rejected after the last retry failed
+			for _, pos := range retry {
+				for _, item := range resp.Items[pos] {
+					failures = append(failures, item.failure())
+				}
+			}
+			break
+		}
+		wait := retryBackoff.NextBackOff()
+		c.logger.Debug().Str("table", table).Str("index", index).Int("documents", len(pending)).Dur("wait", wait).Msg("Retrying rejected documents")
+		select {
+		case <-ctx.Done():
+			atomic.AddUint64(&c.metrics.Errors, uint64(len(pending)+len(failures)))
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	atomic.AddUint64(&c.metrics.Errors, uint64(len(failures)))
+	return c.bulkFailuresError(table, index, len(docs), failures)
+}
+
+func (c *Client) bulkRequest(ctx context.Context, index string, docs [][]byte) (*bulkResponse, error) {
+	resp, err := c.client.Bulk(bytes.NewReader(bytes.Join(docs, nil)),
+		c.client.Bulk.WithContext(ctx),
+		c.client.Bulk.WithIndex(index),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bulk request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		return nil, fmt.Errorf("bulk request failed: %s", resp.String())
+	}
+	var bulkResp bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&bulkResp); err != nil {
+		return nil, fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	return &bulkResp, nil
+}
+
+// bulkFailuresError logs the failed documents grouped by reason, as a mapping conflict usually fails all the
+// documents of a batch the same way, and returns an error with the most common reason
+func (c *Client) bulkFailuresError(table string, index string, total int, failures []bulkItemFailure) error {
+	type reason struct {
+		status int
+		err    bulkItemError
+	}
+	ids := make(map[reason][]string)
+	for _, failure := range failures {
+		r := reason{status: failure.status, err: failure.err}
+		ids[r] = append(ids[r], failure.id)
+	}
+	reasons := make([]reason, 0, len(ids))
+	for r := range ids {
+		reasons = append(reasons, r)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if len(ids[reasons[i]]) != len(ids[reasons[j]]) {
+			return len(ids[reasons[i]]) > len(ids[reasons[j]])
+		}
+		return reasons[i].err.Reason < reasons[j].err.Reason
+	})
+	for _, r := range reasons {
+		c.logger.Error().
+			Str("table", table).
+			Str("index", index).
+			Int("status", r.status).
+			Str("type", r.err.Type).
+			Str("reason", r.err.Reason).
+			Int("documents", len(ids[r])).
+			Strs("ids", sampleIDs(ids[r])).
+			Msg("Failed to index documents")
+	}
+	top := reasons[0]
+	return fmt.Errorf("failed to index %d of %d documents into %s: %s: %s (status %d)", len(failures), total, index, top.err.Type, top.err.Reason, top.status)
+}
+
+// sampleIDs returns the first few IDs, so that a batch failing the same way doesn't log all of them
+func sampleIDs(ids []string) []string {
+	const maxIDs = 5
+	if len(ids) > maxIDs {
+		return ids[:maxIDs]
+	}
+	return ids
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/rs/zerolog"
+)
+
+func TestParseBulkItems(t *testing.T) {
+	resp := &bulkResponse{
+		Errors: true,
+		Items: []map[string]bulkResponseItem{
+			{"index": {ID: "1", Status: 201}},
+			{"index": {ID: "2", Status: 429, Error: &bulkItemError{Type: "es_rejected_execution_exception", Reason: "rejected"}}},
+			{"index": {ID: "3", Status: 400, Error: &bulkItemError{Type: "mapper_parsing_exception", Reason: "failed to parse field [tags]"}}},
+			{"index": {ID: "4", Status: 503, Error: &bulkItemError{Type: "unavailable_shards_exception", Reason: "primary shard is not active"}}},
+		},
+	}
+	retry, failures, err := parseBulkItems(resp, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(retry) != "[1 3]" {
+		t.Errorf("expected retry of [1 3], got %v", retry)
+	}
+	if len(failures) != 1 || failures[0].id != "3" || failures[0].err.Type != "mapper_parsing_exception" {
+		t.Errorf("unexpected failures %+v", failures)
+	}
+
+	if _, _, err := parseBulkItems(resp, 3); err == nil {
+		t.Error("expected error for a response with more items than documents")
+	}
+}
+
+func TestBulkRetries(t *testing.T) {
+	// the first request rejects the second document, which is retried, and fails the third document permanently
+	var (
+		requests   []int
+		requestsMu sync.Mutex
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		docs := 0
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			docs++
+		}
+		docs /= 2
+		requestsMu.Lock()
+		requests = append(requests, docs)
+		first := len(requests) == 1
+		requestsMu.Unlock()
+		if first {
+			fmt.Fprint(w, `{"errors":true,"items":[`+
+				`{"index":{"_id":"a","status":201}},`+
+				`{"index":{"_id":"b","status":429,"error":{"type":"es_rejected_execution_exception","reason":"rejected"}}},`+
+				`{"index":{"_id":"c","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [tags]"}}}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"errors":false,"items":[{"index":{"_id":"b","status":201}}]}`)
+	}))
+	defer srv.Close()
+
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{logger: zerolog.Nop(), client: es}
+	docs := [][]byte{
+		[]byte("{\"index\":{}}\n{\"id\":1}\n"),
+		[]byte("{\"index\":{}}\n{\"id\":2}\n"),
+		[]byte("{\"index\":{}}\n{\"id\":3}\n"),
+	}
+	err = c.bulk(context.Background(), "test_table", "test_table-2023-04-01", docs)
+	if err == nil || !strings.Contains(err.Error(), "failed to index 1 of 3 documents") || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	requestsMu.Lock()
+	defer requestsMu.Unlock()
+	if fmt.Sprint(requests) != "[3 1]" {
+		t.Errorf("expected requests of [3 1] documents, got %v", requests)
+	}
+	if c.metrics.Writes != 2 || c.metrics.Errors != 1 {
+		t.Errorf("expected 2 writes and 1 error, got %+v", c.metrics)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
@@ -13,8 +15,12 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// indexPerSyncLayout is the layout of the sync time in the index names with index_per_sync, as index names must be
+// lowercase
+const indexPerSyncLayout = "2006-01-02t15-04-05"
+
 type Client struct {
-	destination.UnimplementedUnmanagedWriter
+	destination.UnimplementedManagedWriter
 	destination.DefaultReverseTransformer
 	logger      zerolog.Logger
 	spec        specs.Destination
@@ -22,13 +28,18 @@ type Client struct {
 	pluginSpec  Spec
 	client      *elasticsearch.Client
 	typedClient *elasticsearch.TypedClient
+
+	// syncIndices are the indices written to in the sync, by table
+	syncIndices   map[string]string
+	syncIndicesMu sync.Mutex
 }
 
 func New(ctx context.Context, logger zerolog.Logger, destSpec specs.Destination) (destination.Client, error) {
 	var err error
 	c := &Client{
-		logger: logger.With().Str("module", "elasticsearch-dest").Logger(),
-		spec:   destSpec,
+		logger:      logger.With().Str("module", "elasticsearch-dest").Logger(),
+		spec:        destSpec,
+		syncIndices: make(map[string]string),
 	}
 	var spec Spec
 	if err := destSpec.UnmarshalSpec(&spec); err != nil {
@@ -38,6 +49,9 @@ func New(ctx context.Context, logger zerolog.Logger, destSpec specs.Destination)
 	if err := spec.Validate(); err != nil {
 		return nil, err
 	}
+	if (spec.IndexPerSync || spec.ReadAlias || spec.RetainIndices > 0) && destSpec.WriteMode != specs.WriteModeAppend {
+		return nil, fmt.Errorf("index_per_sync, read_alias and retain_indices are only supported in append mode")
+	}
 
 	c.pluginSpec = spec
 	retryBackoff := backoff.NewExponentialBackOff()
@@ -88,7 +102,30 @@ func New(ctx context.Context, logger zerolog.Logger, destSpec specs.Destination)
 	return c, nil
 }
 
-func (*Client) Close(_ context.Context) error {
+// Close points the read aliases to the indices of the sync and deletes the indices out of retention
+func (c *Client) Close(ctx context.Context) error {
+	if !c.pluginSpec.ReadAlias && c.pluginSpec.RetainIndices == 0 {
+		return nil
+	}
+	c.syncIndicesMu.Lock()
+	defer c.syncIndicesMu.Unlock()
+	tables := make([]string, 0, len(c.syncIndices))
+	for table := range c.syncIndices {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		if c.pluginSpec.ReadAlias {
+			if err := c.updateReadAlias(ctx, table, c.syncIndices[table]); err != nil {
+				return err
+			}
+		}
+		if c.pluginSpec.RetainIndices > 0 {
+			if err := c.deleteOldIndices(ctx, table, c.syncIndices[table]); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -108,6 +145,9 @@ func (c *Client) getIndexNamePattern(tableName string) string {
 func (c *Client) getIndexName(tableName string, t time.Time) string {
 	switch c.spec.WriteMode {
 	case specs.WriteModeAppend:
+		if c.pluginSpec.IndexPerSync {
+			return tableName + "-" + t.UTC().Format(indexPerSyncLayout)
+		}
 		return tableName + "-" + t.Format("2006-01-02")
 	case specs.WriteModeOverwrite:
 		return tableName
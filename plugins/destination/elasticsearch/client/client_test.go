@@ -23,7 +23,7 @@ func TestPlugin(t *testing.T) {
 	}
 	destination.PluginTestSuiteRunner(t,
 		func() *destination.Plugin {
-			return destination.NewPlugin("elasticsearch", "development", New, destination.WithManagedWriter())
+			return destination.NewPlugin("elasticsearch", "development", New)
 		},
 		specs.Destination{
 			Spec: &Spec{
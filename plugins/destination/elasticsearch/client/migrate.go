@@ -28,8 +28,25 @@ func (c *Client) Migrate(ctx context.Context, tables schema.Tables) error {
 	return nil
 }
 
+// indexTemplate is the body of a put index template request. The properties of the mappings aren't typed, so that
+// they can be overridden by the mappings of the spec.
+type indexTemplate struct {
+	IndexPatterns []string              `json:"index_patterns"`
+	ComposedOf    []string              `json:"composed_of"`
+	Template      indexTemplateTemplate `json:"template"`
+}
+
+type indexTemplateTemplate struct {
+	Settings *types.IndexSettings `json:"settings,omitempty"`
+	Mappings indexTemplateMapping `json:"mappings"`
+}
+
+type indexTemplateMapping struct {
+	Properties map[string]any `json:"properties"`
+}
+
 func (c *Client) getIndexTemplate(table *schema.Table) (string, error) {
-	properties := map[string]types.Property{}
+	properties := map[string]any{}
 	for _, col := range table.Columns {
 		switch col.Type {
 		case schema.TypeBool:
@@ -71,19 +88,22 @@ func (c *Client) getIndexTemplate(table *schema.Table) (string, error) {
 			properties[col.Name] = types.NewIntegerNumberProperty()
 		}
 	}
-	tmp := types.IndexTemplate{
-		AllowAutoCreate: nil,
-		ComposedOf:      []string{},
-		DataStream:      nil,
-		IndexPatterns:   []string{c.getIndexNamePattern(table.Name)},
-		Meta_:           nil,
-		Priority:        nil,
-		Template: &types.IndexTemplateSummary{
-			Mappings: &types.TypeMapping{
+	for column, mapping := range c.pluginSpec.Mappings[table.Name] {
+		properties[column] = mapping
+	}
+	tmp := indexTemplate{
+		IndexPatterns: []string{c.getIndexNamePattern(table.Name)},
+		ComposedOf:    []string{},
+		Template: indexTemplateTemplate{
+			Mappings: indexTemplateMapping{
 				Properties: properties,
 			},
 		},
-		Version: nil,
+	}
+	if c.pluginSpec.ILMPolicy != "" {
+		tmp.Template.Settings = &types.IndexSettings{
+			Lifecycle: &types.IndexSettingsLifecycle{Name: c.pluginSpec.ILMPolicy},
+		}
 	}
 	b, err := json.Marshal(tmp)
 	return string(b), err
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/cloudquery/plugin-sdk/specs"
+)
+
+func TestGetIndexTemplate(t *testing.T) {
+	c := &Client{
+		spec: specs.Destination{WriteMode: specs.WriteModeAppend},
+		pluginSpec: Spec{
+			ILMPolicy: "cloudquery",
+			Mappings: map[string]map[string]map[string]any{
+				"test_table": {"tags": {"type": "keyword"}},
+				"other":      {"name": {"type": "keyword"}},
+			},
+		},
+	}
+	table := &schema.Table{Name: "test_table", Columns: schema.ColumnList{
+		{Name: "name", Type: schema.TypeString},
+		{Name: "tags", Type: schema.TypeStringArray},
+	}}
+	tmpl, err := c.getIndexTemplate(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(tmpl), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"index_patterns": []any{"test_table-*"},
+		"composed_of":    []any{},
+		"template": map[string]any{
+			"settings": map[string]any{"lifecycle": map[string]any{"name": "cloudquery"}},
+			"mappings": map[string]any{
+				"properties": map[string]any{
+					"name": map[string]any{"type": "text"},
+					"tags": map[string]any{"type": "keyword"},
+				},
+			},
+		},
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("unexpected index template:\nwant %s\ngot  %s", wantJSON, gotJSON)
+	}
+}
+
+func TestGetIndexName(t *testing.T) {
+	syncTime := time.Date(2023, 4, 1, 10, 30, 15, 0, time.UTC)
+	cases := []struct {
+		writeMode    specs.WriteMode
+		indexPerSync bool
+		want         string
+	}{
+		{writeMode: specs.WriteModeAppend, want: "test_table-2023-04-01"},
+		{writeMode: specs.WriteModeAppend, indexPerSync: true, want: "test_table-2023-04-01t10-30-15"},
+		{writeMode: specs.WriteModeOverwrite, want: "test_table"},
+	}
+	for _, tc := range cases {
+		c := &Client{spec: specs.Destination{WriteMode: tc.writeMode}, pluginSpec: Spec{IndexPerSync: tc.indexPerSync}}
+		if got := c.getIndexName("test_table", syncTime); got != tc.want {
+			t.Errorf("expected index %s, got %s", tc.want, got)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package client
 
-import "runtime"
+import (
+	"fmt"
+	"runtime"
+)
 
 type Spec struct {
 	Addresses []string `json:"addresses"` // A list of Elasticsearch nodes to use.
@@ -17,6 +20,14 @@ type Spec struct {
 	CACert string `json:"ca_cert"`
 
 	Concurrency int `json:"concurrency"` // Number of concurrent worker goroutines to use for indexing. (Default: number of CPUs)
+
+	// Mappings override the generated mappings of columns, by table and column name.
+	Mappings  map[string]map[string]map[string]any `json:"mappings,omitempty"`
+	ILMPolicy string                               `json:"ilm_policy,omitempty"` // Name of the ILM policy set in the index templates.
+
+	IndexPerSync  bool `json:"index_per_sync,omitempty"` // Create an index for every sync instead of every day, in append mode.
+	ReadAlias     bool `json:"read_alias,omitempty"`     // Point an alias named after the table to the newest index after every sync. Requires index_per_sync.
+	RetainIndices int  `json:"retain_indices,omitempty"` // Number of the newest indices of every table kept after every sync. Requires index_per_sync. (Default: all)
 }
 
 func (s *Spec) SetDefaults() {
@@ -28,6 +39,20 @@ func (s *Spec) SetDefaults() {
 	}
 }
 
-func (*Spec) Validate() error {
+func (s *Spec) Validate() error {
+	if s.RetainIndices < 0 {
+		return fmt.Errorf("retain_indices should be positive")
+	}
+	// daily indices hold the resources of every sync of the day, so an alias or retention over them would count days
+	if (s.ReadAlias || s.RetainIndices > 0) && !s.IndexPerSync {
+		return fmt.Errorf("read_alias and retain_indices require index_per_sync")
+	}
+	for table, columns := range s.Mappings {
+		for column, mapping := range columns {
+			if len(mapping) == 0 {
+				return fmt.Errorf("mapping of column %s of table %s is empty", column, table)
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,27 @@
+package client
+
+import "testing"
+
+func TestSpecValidate(t *testing.T) {
+	cases := []struct {
+		spec    Spec
+		wantErr bool
+	}{
+		{spec: Spec{}},
+		{spec: Spec{IndexPerSync: true, ReadAlias: true, RetainIndices: 3}},
+		{spec: Spec{ReadAlias: true}, wantErr: true},
+		{spec: Spec{RetainIndices: 3}, wantErr: true},
+		{spec: Spec{IndexPerSync: true, RetainIndices: -1}, wantErr: true},
+		{spec: Spec{Mappings: map[string]map[string]map[string]any{"test_table": {"id": {}}}}, wantErr: true},
+	}
+	for _, tc := range cases {
+		tc.spec.SetDefaults()
+		err := tc.spec.Validate()
+		if tc.wantErr && err == nil {
+			t.Errorf("expected error for %+v", tc.spec)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("unexpected error for %+v: %v", tc.spec, err)
+		}
+	}
+}
@@ -1,63 +1,114 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/cloudquery/plugin-sdk/plugins/destination"
 	"github.com/cloudquery/plugin-sdk/schema"
 	"github.com/cloudquery/plugin-sdk/specs"
 	"github.com/segmentio/fasthash/fnv1a"
 )
 
-func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, resources [][]any) error {
-	if len(resources) == 0 {
-		return nil
+// Write indexes the resources of every table in batches of up to batch_size documents and batch_size_bytes bytes.
+// The batches of different tables are indexed concurrently. Documents that failed to be indexed are logged and
+// counted as errors in the metrics, and don't stop the sync.
+func (c *Client) Write(ctx context.Context, tables schema.Tables, res <-chan *destination.ClientResource) error {
+	var wg sync.WaitGroup
+	workers := make(map[string]chan []any)
+	for r := range res {
+		ch, ok := workers[r.TableName]
+		if !ok {
+			ch = make(chan []any)
+			workers[r.TableName] = ch
+			table := tables.Get(r.TableName)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.tableWorker(ctx, table, ch)
+			}()
+		}
+		ch <- r.Data
+	}
+	for _, ch := range workers {
+		close(ch)
 	}
+	wg.Wait()
+	return ctx.Err()
+}
 
-	var buf bytes.Buffer
+// tableWorker batches the documents of the table received on ch, and indexes every batch
+func (c *Client) tableWorker(ctx context.Context, table *schema.Table, ch <-chan []any) {
 	pks := pkIndexes(table) // do some work up front to avoid doing it for every resource
-	// get the sync time from the first resource in the batch (here we assume that all resources in the batch
-	// have the same sync time. At the moment this assumption holds.)
-	syncTime := resources[0][table.Columns.Index(schema.CqSyncTimeColumn.Name)].(time.Time)
-	for _, r := range resources {
-		doc := map[string]any{}
-		for i, col := range table.Columns {
-			doc[col.Name] = r[i]
-		}
-		data, err := json.Marshal(doc)
+	syncTimeIndex := table.Columns.Index(schema.CqSyncTimeColumn.Name)
+	var docs [][]byte
+	var syncTime time.Time
+	sizeBytes := 0
+	for r := range ch {
+		doc, err := c.document(table, pks, r)
 		if err != nil {
-			return fmt.Errorf("failed to marshal JSON: %w", err)
+			atomic.AddUint64(&c.metrics.Errors, 1)
+			c.logger.Error().Err(err).Str("table", table.Name).Msg("Failed to create document")
+			continue
 		}
-
-		var meta []byte
-		if c.spec.WriteMode == specs.WriteModeOverwrite || c.spec.WriteMode == specs.WriteModeOverwriteDeleteStale {
-			docID := fmt.Sprint(resourceID(r, pks))
-			meta = []byte(fmt.Sprintf(`{"index":{"_id":"%s"}}%s`, docID, "\n"))
-		} else {
-			meta = []byte(`{"index":{}}` + "\n")
+		if len(docs) > 0 && (len(docs) >= c.spec.BatchSize || sizeBytes+len(doc) > c.spec.BatchSizeBytes) {
+			c.writeDocuments(ctx, table.Name, syncTime, docs)
+			docs = nil
+			sizeBytes = 0
+		}
+		if len(docs) == 0 {
+			// the index of the batch is named after the sync time of its first resource. All the resources of
+			// a batch have the same sync time at the moment.
+			syncTime = r[syncTimeIndex].(time.Time)
 		}
-		data = append(data, "\n"...)
-		buf.Grow(len(meta) + len(data))
-		buf.Write(meta)
-		buf.Write(data)
+		docs = append(docs, doc)
+		sizeBytes += len(doc)
+	}
+	if len(docs) > 0 {
+		c.writeDocuments(ctx, table.Name, syncTime, docs)
 	}
-	index := c.getIndexName(table.Name, syncTime)
-	resp, err := c.client.Bulk(bytes.NewReader(buf.Bytes()),
-		c.client.Bulk.WithContext(ctx),
-		c.client.Bulk.WithIndex(index),
-	)
+}
+
+// writeDocuments indexes a batch of documents of the table. Failures are counted in the metrics by bulk.
+func (c *Client) writeDocuments(ctx context.Context, table string, syncTime time.Time, docs [][]byte) {
+	index := c.getIndexName(table, syncTime)
+	if err := c.bulk(ctx, table, index, docs); err != nil {
+		c.logger.Error().Err(err).Str("table", table).Str("index", index).Int("documents", len(docs)).Msg("Failed to write batch")
+		return
+	}
+	c.syncIndicesMu.Lock()
+	c.syncIndices[table] = index
+	c.syncIndicesMu.Unlock()
+}
+
+// document returns the bulk action indexing the resource
+func (c *Client) document(table *schema.Table, pks []int, r []any) ([]byte, error) {
+	doc := map[string]any{}
+	for i, col := range table.Columns {
+		doc[col.Name] = r[i]
+	}
+	data, err := json.Marshal(doc)
 	if err != nil {
-		return fmt.Errorf("failed to create bulk request: %w", err)
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
 	}
-	defer resp.Body.Close()
-	if resp.IsError() {
-		return fmt.Errorf("bulk request failed: %s", resp.String())
+
+	var meta []byte
+	if c.spec.WriteMode == specs.WriteModeOverwrite || c.spec.WriteMode == specs.WriteModeOverwriteDeleteStale {
+		docID := fmt.Sprint(resourceID(r, pks))
+		meta = []byte(fmt.Sprintf(`{"index":{"_id":"%s"}}%s`, docID, "\n"))
+	} else {
+		meta = []byte(`{"index":{}}` + "\n")
 	}
-	return err
+	action := make([]byte, 0, len(meta)+len(data)+1)
+	action = append(action, meta...)
+	action = append(action, data...)
+	action = append(action, "\n"...)
+	return action, nil
 }
 
 func pkIndexes(table *schema.Table) []int {
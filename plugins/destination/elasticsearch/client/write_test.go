@@ -0,0 +1,80 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudquery/plugin-sdk/plugins/destination"
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/rs/zerolog"
+)
+
+func TestWriteBatches(t *testing.T) {
+	// every request fails its last document permanently
+	var (
+		requests   []string
+		requestsMu sync.Mutex
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		docs := 0
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			docs++
+		}
+		docs /= 2
+		requestsMu.Lock()
+		requests = append(requests, fmt.Sprintf("%s:%d", strings.Split(r.URL.Path, "/")[1], docs))
+		requestsMu.Unlock()
+		items := make([]string, docs)
+		for i := range items {
+			items[i] = `{"index":{"status":201}}`
+		}
+		items[docs-1] = `{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`
+		fmt.Fprintf(w, `{"errors":true,"items":[%s]}`, strings.Join(items, ","))
+	}))
+	defer srv.Close()
+
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{
+		logger:      zerolog.Nop(),
+		client:      es,
+		spec:        specs.Destination{WriteMode: specs.WriteModeAppend, BatchSize: 2, BatchSizeBytes: 1 << 20},
+		syncIndices: make(map[string]string),
+	}
+	columns := schema.ColumnList{schema.CqSyncTimeColumn, {Name: "id", Type: schema.TypeInt}}
+	tables := schema.Tables{{Name: "test_a", Columns: columns}, {Name: "test_b", Columns: columns}}
+	syncTime := time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+	res := make(chan *destination.ClientResource, 5)
+	for i, table := range []string{"test_a", "test_a", "test_a", "test_b", "test_b"} {
+		res <- &destination.ClientResource{TableName: table, Data: []any{syncTime, int64(i)}}
+	}
+	close(res)
+	if err := c.Write(context.Background(), tables, res); err != nil {
+		t.Fatal(err)
+	}
+
+	requestsMu.Lock()
+	defer requestsMu.Unlock()
+	sort.Strings(requests)
+	if want := "[test_a-2023-04-01:1 test_a-2023-04-01:2 test_b-2023-04-01:2]"; fmt.Sprint(requests) != want {
+		t.Errorf("expected requests %s, got %v", want, requests)
+	}
+	if m := c.Metrics(); m.Writes != 2 || m.Errors != 3 {
+		t.Errorf("expected 2 writes and 3 errors, got %+v", m)
+	}
+}
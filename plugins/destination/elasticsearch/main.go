@@ -12,8 +12,6 @@ const (
 )
 
 func main() {
-	p := destination.NewPlugin("elasticsearch", plugin.Version, client.New,
-		destination.WithManagedWriter(),
-	)
+	p := destination.NewPlugin("elasticsearch", plugin.Version, client.New)
 	serve.Destination(p, serve.WithDestinationSentryDSN(sentryDSN))
 }
@@ -6,6 +6,7 @@ import (
 
 	"cloud.google.com/go/storage"
 
+	"github.com/cloudquery/cloudquery/plugins/destination/internal/manifest"
 	"github.com/cloudquery/filetypes"
 	"github.com/cloudquery/plugin-sdk/plugins/destination"
 	"github.com/cloudquery/plugin-sdk/specs"
@@ -21,6 +22,8 @@ type Client struct {
 
 	gcsClient *storage.Client
 	bucket    *storage.BucketHandle
+	// manifests is nil unless manifests or _SUCCESS markers are written
+	manifests *manifest.Manifests
 	*filetypes.Client
 }
 
@@ -47,6 +50,9 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 		return nil, fmt.Errorf("failed to create filetypes client: %w", err)
 	}
 	c.Client = filetypesClient
+	if c.pluginSpec.Manifest || c.pluginSpec.SuccessMarker {
+		c.manifests = manifest.New(c.pluginSpec.Path, string(c.pluginSpec.Format))
+	}
 
 	c.gcsClient, err = storage.NewClient(ctx)
 	if err != nil {
@@ -65,6 +71,24 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 	return c, nil
 }
 
-func (*Client) Close(ctx context.Context) error {
+// Close writes the manifests and _SUCCESS markers of the objects written during the sync
+func (c *Client) Close(ctx context.Context) error {
+	if c.manifests == nil {
+		return nil
+	}
+	files, err := c.manifests.Files(c.pluginSpec.Manifest, c.pluginSpec.SuccessMarker)
+	if err != nil {
+		return fmt.Errorf("failed to create manifests: %w", err)
+	}
+	for _, f := range files {
+		w := c.bucket.Object(f.Key).NewWriter(ctx)
+		if _, err := w.Write(f.Body); err != nil {
+			_ = w.Close()
+			return fmt.Errorf("failed to write %s to GCS: %w", f.Key, err)
+		}
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("failed to write %s to GCS: %w", f.Key, err)
+		}
+	}
 	return nil
 }
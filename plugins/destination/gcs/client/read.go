@@ -3,6 +3,8 @@ package client
 import (
 	"context"
 	"fmt"
+	"path"
+	"strings"
 
 	"github.com/cloudquery/plugin-sdk/schema"
 )
@@ -11,8 +13,9 @@ func (c *Client) Read(ctx context.Context, table *schema.Table, sourceName strin
 	if !c.pluginSpec.NoRotate {
 		return fmt.Errorf("reading is not supported when no_rotate is false. Table: %q; Source: %q", table.Name, sourceName)
 	}
-	name := fmt.Sprintf("%s/%s.%s", c.pluginSpec.Path, table.Name, c.pluginSpec.Format)
-	r, err := c.bucket.Object(name).NewReader(ctx)
+	name := strings.ReplaceAll(c.pluginSpec.Path, PathVarTable, table.Name)
+	name = strings.ReplaceAll(name, PathVarSource, sourceName)
+	r, err := c.bucket.Object(path.Clean(name)).NewReader(ctx)
 	if err != nil {
 		return err
 	}
@@ -2,19 +2,67 @@ package client
 
 import (
 	"context"
-	"fmt"
+	"path"
+	"strings"
+	"time"
 
+	"github.com/cloudquery/cloudquery/plugins/destination/internal/manifest"
 	"github.com/cloudquery/plugin-sdk/schema"
 	"github.com/google/uuid"
 )
 
-func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, data [][]any) error {
-	name := fmt.Sprintf("%s/%s.%s.%s", c.pluginSpec.Path, table.Name, c.pluginSpec.Format, uuid.NewString())
-	if c.pluginSpec.NoRotate {
-		name = fmt.Sprintf("%s/%s.%s", c.pluginSpec.Path, table.Name, c.pluginSpec.Format)
+const (
+	PathVarTable  = "{{TABLE}}"
+	PathVarUUID   = "{{UUID}}"
+	PathVarSource = "{{SOURCE}}"
+	PathVarSyncID = "{{SYNC_ID}}"
+	YearVar       = "{{YEAR}}"
+	MonthVar      = "{{MONTH}}"
+	DayVar        = "{{DAY}}"
+	HourVar       = "{{HOUR}}"
+	MinuteVar     = "{{MINUTE}}"
+)
+
+func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, data [][]any) (err error) {
+	if len(data) == 0 {
+		return nil
 	}
-	w := c.gcsClient.Bucket(c.pluginSpec.Bucket).Object(name).NewWriter(ctx)
-	defer w.Close()
+	defer func() {
+		// the sync continues after a failed write, so the objects of the directories written to are incomplete
+		if err != nil && c.manifests != nil {
+			c.manifests.WriteFailed()
+		}
+	}()
+
+	timeNow := time.Now().UTC()
+
+	for _, batch := range manifest.SplitBySync(c.pluginSpec.Path, table, data) {
+		name := replacePathVariables(c.pluginSpec.Path, table.Name, batch.Source, batch.SyncID, uuid.NewString(), timeNow)
+		w := c.bucket.Object(name).NewWriter(ctx)
+		if err := c.Client.WriteTableBatchFile(w, table, batch.Data); err != nil {
+			_ = w.Close()
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		if c.manifests != nil {
+			c.manifests.Add(name, table, batch.Source, batch.SyncID, len(batch.Data))
+		}
+	}
+
+	return nil
+}
 
-	return c.Client.WriteTableBatchFile(w, table, data)
+func replacePathVariables(specPath, table, source, syncID, fileIdentifier string, t time.Time) string {
+	name := strings.ReplaceAll(specPath, PathVarTable, table)
+	name = strings.ReplaceAll(name, PathVarSource, source)
+	name = strings.ReplaceAll(name, PathVarSyncID, syncID)
+	name = strings.ReplaceAll(name, PathVarUUID, fileIdentifier)
+	name = strings.ReplaceAll(name, YearVar, t.Format("2006"))
+	name = strings.ReplaceAll(name, MonthVar, t.Format("01"))
+	name = strings.ReplaceAll(name, DayVar, t.Format("02"))
+	name = strings.ReplaceAll(name, HourVar, t.Format("15"))
+	name = strings.ReplaceAll(name, MinuteVar, t.Format("04"))
+	return path.Clean(name)
 }
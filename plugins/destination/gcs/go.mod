@@ -4,12 +4,15 @@ go 1.19
 
 require (
 	cloud.google.com/go/storage v1.28.1
+	github.com/cloudquery/cloudquery/plugins/destination/internal/manifest v0.0.0-00010101000000-000000000000
 	github.com/cloudquery/filetypes v1.6.2
 	github.com/cloudquery/plugin-sdk v1.44.2
 	github.com/google/uuid v1.3.0
 	github.com/rs/zerolog v1.29.0
 )
 
+replace github.com/cloudquery/cloudquery/plugins/destination/internal/manifest => ../internal/manifest
+
 replace github.com/apache/arrow/go/v12 => github.com/cloudquery/arrow/go/v12 v12.0.0-20230317130341-c648117570af
 
 require (
@@ -21,6 +24,7 @@ require (
 	github.com/goccy/go-json v0.10.2 // indirect
 	github.com/golang/snappy v0.0.4 // indirect
 	github.com/google/flatbuffers v2.0.8+incompatible // indirect
+	github.com/google/go-cmp v0.5.9 // indirect
 	github.com/klauspost/asmfmt v1.3.2 // indirect
 	github.com/klauspost/compress v1.16.3 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.4 // indirect
@@ -47,7 +51,6 @@ require (
 	github.com/ghodss/yaml v1.0.0 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect; indirect // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
-	github.com/google/go-cmp v0.5.9 // indirect
 	github.com/googleapis/enterprise-certificate-proxy v0.2.3 // indirect; indirect // indirect
 	github.com/googleapis/gax-go/v2 v2.7.1 // indirect
 	github.com/grpc-ecosystem/go-grpc-middleware/providers/zerolog/v2 v2.0.0-rc.3 // indirect
@@ -26,6 +26,10 @@ type Client struct {
 
 	mu     sync.Mutex // protects client during session creation
 	client *gremlingo.DriverRemoteConnection
+
+	// parents maps the tables that are relations of another table to their parent table
+	parents     map[string]string
+	parentsLock sync.RWMutex
 }
 
 var AnonT = gremlingo.T__
@@ -33,8 +37,9 @@ var AnonT = gremlingo.T__
 func New(ctx context.Context, logger zerolog.Logger, destSpec specs.Destination) (destination.Client, error) {
 	var err error
 	c := &Client{
-		logger: logger.With().Str("module", "gremlin").Logger(),
-		spec:   destSpec,
+		logger:  logger.With().Str("module", "gremlin").Logger(),
+		spec:    destSpec,
+		parents: make(map[string]string),
 	}
 	var spec Spec
 	if err := destSpec.UnmarshalSpec(&spec); err != nil {
@@ -15,12 +15,14 @@ func (c *Client) DeleteStale(ctx context.Context, tables schema.Tables, source s
 	}
 	defer closer()
 
-	for _, table := range tables {
+	for _, table := range tables.FlattenTables() {
+		// the edges of the stale vertices are dropped explicitly, as not all graph databases drop them with the vertex
 		g := gremlingo.Traversal_().WithRemote(session).
 			V().
 			HasLabel(table.Name).
 			Has("_cq_source_name", source).
 			Has("_cq_sync_time", gremlingo.P.Lt(syncTime)).
+			SideEffect(AnonT.BothE().Drop()).
 			SideEffect(AnonT.Drop())
 		if err := <-g.Iterate(); err != nil {
 			return err
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+
+	gremlingo "github.com/apache/tinkerpop/gremlin-go/v3/driver"
+	"github.com/cloudquery/plugin-sdk/schema"
+)
+
+// registerRelations records the table as the parent of its relations
+func (c *Client) registerRelations(table *schema.Table) {
+	c.parentsLock.Lock()
+	defer c.parentsLock.Unlock()
+	for _, relation := range table.Relations {
+		c.parents[relation.Name] = table.Name
+	}
+}
+
+// parent returns the parent table of the table, or an empty string if it's unknown
+func (c *Client) parent(table string) string {
+	c.parentsLock.RLock()
+	defer c.parentsLock.RUnlock()
+	return c.parents[table]
+}
+
+// edgeTables returns the parent of the table that edges to its vertices are created from, and the relations of
+// the table that edges are created to. Vertices are matched by _cq_id and _cq_parent_id.
+func (c *Client) edgeTables(table *schema.Table) (string, []string) {
+	if table.Columns.Get(schema.CqIDColumn.Name) == nil {
+		return "", nil
+	}
+	var parent string
+	if p := c.parent(table.Name); p != "" && table.Columns.Get(schema.CqParentIDColumn.Name) != nil {
+		parent = p
+	}
+	var relations []string
+	for _, relation := range table.Relations {
+		if relation.Columns.Get(schema.CqParentIDColumn.Name) != nil {
+			relations = append(relations, relation.Name)
+		}
+	}
+	return parent, relations
+}
+
+// writeEdges creates the edges from the parent vertex of every row, and to the vertices of the relations of the
+// table that were written before the rows. Every row is linked in its own side effect, so that a missing vertex
+// doesn't stop the traversal.
+func (c *Client) writeEdges(ctx context.Context, session *gremlingo.DriverRemoteConnection, table *schema.Table, rows []map[string]any) error {
+	parent, relations := c.edgeTables(table)
+	if parent == "" && len(relations) == 0 {
+		return nil
+	}
+	g := gremlingo.Traversal_().WithRemote(session).Inject(0)
+	for _, row := range rows {
+		id := row[schema.CqIDColumn.Name]
+		if id == nil {
+			continue
+		}
+		if parentID := row[schema.CqParentIDColumn.Name]; parent != "" && parentID != nil {
+			g = g.SideEffect(
+				linkEdge(
+					AnonT.V().HasLabel(parent).Has(schema.CqIDColumn.Name, parentID).As("p").
+						V().HasLabel(table.Name).Has(schema.CqIDColumn.Name, id),
+					c.pluginSpec.edgeLabel(table.Name),
+				),
+			)
+		}
+		for _, relation := range relations {
+			g = g.SideEffect(
+				linkEdge(
+					AnonT.V().HasLabel(table.Name).Has(schema.CqIDColumn.Name, id).As("p").
+						V().HasLabel(relation).Has(schema.CqParentIDColumn.Name, id),
+					c.pluginSpec.edgeLabel(relation),
+				),
+			)
+		}
+	}
+	return c.iterate(ctx, g)
+}
+
+// linkEdge creates the edge from the vertex labeled p to the current vertex, unless it exists, and drops the edges
+// with the same label from other vertices, which are no longer the parent
+func linkEdge(child *gremlingo.GraphTraversal, label string) *gremlingo.GraphTraversal {
+	return child.
+		SideEffect(AnonT.InE(label).Where(AnonT.OutV().Where(gremlingo.P.Neq("p"))).Drop()).
+		Coalesce(
+			AnonT.InE(label).Where(AnonT.OutV().As("p")),
+			AnonT.AddE(label).From("p"),
+		)
+}
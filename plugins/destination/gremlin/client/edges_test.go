@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEdgeTables(t *testing.T) {
+	child := &schema.Table{
+		Name:    "test_child",
+		Columns: schema.ColumnList{schema.CqIDColumn, schema.CqParentIDColumn},
+	}
+	parent := &schema.Table{
+		Name:      "test_parent",
+		Columns:   schema.ColumnList{schema.CqIDColumn, schema.CqParentIDColumn},
+		Relations: schema.Tables{child},
+	}
+	c := &Client{parents: make(map[string]string)}
+
+	// the parent of the child is only known once the parent was seen
+	p, relations := c.edgeTables(child)
+	require.Empty(t, p)
+	require.Empty(t, relations)
+
+	require.NoError(t, c.Migrate(context.Background(), schema.Tables{parent}))
+	p, relations = c.edgeTables(child)
+	require.Equal(t, "test_parent", p)
+	require.Empty(t, relations)
+	p, relations = c.edgeTables(parent)
+	require.Empty(t, p)
+	require.Equal(t, []string{"test_child"}, relations)
+}
+
+func TestSpecEdgeLabel(t *testing.T) {
+	spec := Spec{Endpoint: "localhost", EdgeLabels: map[string]string{"test_child": "CONTAINS"}}
+	spec.SetDefaults()
+	require.NoError(t, spec.Validate())
+	require.Equal(t, "HAS_CHILD", spec.edgeLabel("test_other"))
+	require.Equal(t, "CONTAINS", spec.edgeLabel("test_child"))
+
+	spec.EdgeLabels["test_other"] = ""
+	require.ErrorContains(t, spec.Validate(), "empty edge label for table test_other")
+}
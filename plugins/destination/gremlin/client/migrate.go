@@ -6,7 +6,11 @@ import (
 	"github.com/cloudquery/plugin-sdk/schema"
 )
 
-// Migrate tables. Like neo4j, gremlin does not have a schema, so this is a no-op.
-func (*Client) Migrate(ctx context.Context, tables schema.Tables) error {
+// Migrate tables. Like neo4j, gremlin does not have a schema, so only the relations of the tables are recorded
+// for creating edges.
+func (c *Client) Migrate(ctx context.Context, tables schema.Tables) error {
+	for _, table := range tables.FlattenTables() {
+		c.registerRelations(table)
+	}
 	return nil
 }
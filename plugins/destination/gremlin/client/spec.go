@@ -24,8 +24,14 @@ type Spec struct {
 
 	// Connection settings
 	MaxConcurrentConnections int `json:"max_concurrent_connections"`
+
+	// Edges between the vertices of parent and relation tables
+	EdgeLabel  string            `json:"edge_label"`
+	EdgeLabels map[string]string `json:"edge_labels"`
 }
 
+const defaultEdgeLabel = "HAS_CHILD"
+
 type authMode string
 
 const (
@@ -62,6 +68,18 @@ func (s *Spec) SetDefaults() {
 	if s.MaxConcurrentConnections < 1 {
 		s.MaxConcurrentConnections = runtime.NumCPU()
 	}
+
+	if s.EdgeLabel == "" {
+		s.EdgeLabel = defaultEdgeLabel
+	}
+}
+
+// edgeLabel returns the label of the edges from the vertices of the parent table to the vertices of the relation
+func (s *Spec) edgeLabel(relation string) string {
+	if label, ok := s.EdgeLabels[relation]; ok {
+		return label
+	}
+	return s.EdgeLabel
 }
 
 func (s *Spec) Validate() error {
@@ -77,6 +95,11 @@ func (s *Spec) Validate() error {
 	if s.AuthMode == authModeNone && (s.Username != "" || s.Password != "") {
 		return fmt.Errorf("username or password specified with auth_mode %q. Set auth mode to %q or remove username and password", authModeNone, authModeBasic)
 	}
+	for table, label := range s.EdgeLabels {
+		if label == "" {
+			return fmt.Errorf("empty edge label for table %s in edge_labels", table)
+		}
+	}
 
 	return nil
 }
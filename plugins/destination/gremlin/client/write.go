@@ -12,6 +12,9 @@ import (
 )
 
 func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, resources [][]any) error {
+	// the relations are registered before the vertices are written, and the edges are written after them, so that
+	// a parent and a child written concurrently always have their edge created by one of them
+	c.registerRelations(table)
 	session, closer, err := c.newSession()
 	if err != nil {
 		return err
@@ -60,6 +63,15 @@ func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, resou
 		}
 	}
 
+	if err := c.iterate(ctx, g); err != nil {
+		return err
+	}
+	return c.writeEdges(ctx, session, table, rows)
+}
+
+// iterate runs the traversal, retrying on ConcurrentModificationException
+func (c *Client) iterate(ctx context.Context, g *gremlingo.GraphTraversal) error {
+	var err error
 	bo := backoff.NewExponentialBackOff()
 	retryCount := 0
 
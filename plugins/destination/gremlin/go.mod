@@ -9,6 +9,7 @@ require (
 	github.com/cenkalti/backoff/v4 v4.2.0
 	github.com/cloudquery/plugin-sdk v1.44.2
 	github.com/rs/zerolog v1.29.0
+	github.com/stretchr/testify v1.8.2
 )
 
 require (
@@ -26,7 +27,6 @@ require (
 	github.com/gorilla/websocket v1.5.0 // indirect
 	github.com/nicksnyder/go-i18n/v2 v2.2.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.8.2 // indirect
 	golang.org/x/exp v0.0.0-20230321023759-10a507213a29 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
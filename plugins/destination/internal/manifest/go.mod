@@ -0,0 +1,14 @@
+module github.com/cloudquery/cloudquery/plugins/destination/internal/manifest
+
+go 1.19
+
+require (
+	github.com/cloudquery/plugin-sdk v1.44.2
+	github.com/google/go-cmp v0.5.9
+)
+
+require (
+	github.com/google/uuid v1.3.0 // indirect
+	github.com/thoas/go-funk v0.9.3 // indirect
+	golang.org/x/exp v0.0.0-20230321023759-10a507213a29 // indirect
+)
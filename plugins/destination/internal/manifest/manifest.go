@@ -0,0 +1,243 @@
+// Package manifest tracks the objects written by the s3, gcs and azblob destinations during a sync, to write the
+// manifests and _SUCCESS markers of the directories they were written to.
+package manifest
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+)
+
+const (
+	// the path variables of the destinations the objects are grouped by
+	pathVarTable  = "{{TABLE}}"
+	pathVarSource = "{{SOURCE}}"
+	pathVarSyncID = "{{SYNC_ID}}"
+
+	manifestFileName      = "_manifest.json"
+	successMarkerFileName = "_SUCCESS"
+	// syncIDLayout formats the sync time of the resources as the {{SYNC_ID}} of the objects they are written to
+	syncIDLayout = "20060102T150405Z"
+)
+
+type manifestObject struct {
+	Key  string `json:"key"`
+	Rows int    `json:"rows"`
+}
+
+type manifestColumn struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	PrimaryKey bool   `json:"primary_key,omitempty"`
+	NotNull    bool   `json:"not_null,omitempty"`
+	Unique     bool   `json:"unique,omitempty"`
+}
+
+// manifest lists the objects of a table that were written to a directory during the sync
+type manifest struct {
+	Table   string           `json:"table"`
+	Source  string           `json:"source,omitempty"`
+	SyncID  string           `json:"sync_id,omitempty"`
+	Format  string           `json:"format"`
+	Rows    int              `json:"rows"`
+	Objects []manifestObject `json:"objects"`
+	Columns []manifestColumn `json:"columns"`
+
+	// rows of every object. An object that is written again (with no_rotate) replaces the previous one.
+	objects map[string]int
+}
+
+// File is an object written when the destination is closed
+type File struct {
+	Key  string
+	Body []byte
+}
+
+// Manifests tracks the objects written during the sync, so that the manifests and _SUCCESS markers can be
+// written at the end of it
+type Manifests struct {
+	specPath  string
+	format    string
+	lock      sync.Mutex
+	manifests map[string]*manifest
+	dirs      map[string]bool
+	// failed is set when an object failed to be written, so that no _SUCCESS marker is written
+	failed bool
+}
+
+// New returns the manifests of a destination writing objects to specPath in the given format
+func New(specPath string, format string) *Manifests {
+	return &Manifests{
+		specPath:  specPath,
+		format:    format,
+		manifests: make(map[string]*manifest),
+		dirs:      make(map[string]bool),
+	}
+}
+
+// manifestKey returns the key of the manifest of the table in the directory of the object. Objects of different
+// tables can share a directory when the path has no {{TABLE}} directory, so their manifests are named after the
+// table instead.
+func (m *Manifests) manifestKey(objectKey string, table string) string {
+	name := manifestFileName
+	if !strings.Contains(path.Dir(m.specPath), pathVarTable) {
+		name = "_" + table + manifestFileName
+	}
+	return path.Join(path.Dir(objectKey), name)
+}
+
+// Add records an object written with the rows of the table
+func (m *Manifests) Add(objectKey string, table *schema.Table, source string, syncID string, rows int) {
+	key := m.manifestKey(objectKey, table.Name)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.dirs[path.Dir(objectKey)] = true
+	mf := m.manifests[key]
+	if mf == nil {
+		mf = &manifest{
+			Table:   table.Name,
+			Format:  m.format,
+			Columns: manifestColumns(table),
+			objects: make(map[string]int),
+		}
+		m.manifests[key] = mf
+	}
+	mf.Source = source
+	mf.SyncID = syncID
+	mf.objects[objectKey] = rows
+}
+
+// WriteFailed records that an object failed to be written during the sync
+func (m *Manifests) WriteFailed() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.failed = true
+}
+
+// Files returns the manifests if writeManifests is true, followed by the _SUCCESS markers of every directory objects
+// were written to if successMarker is true and no object failed to be written
+func (m *Manifests) Files(writeManifests bool, successMarker bool) ([]File, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var files []File
+	if writeManifests {
+		for _, key := range sortedKeys(m.manifests) {
+			mf := m.manifests[key]
+			mf.Rows = 0
+			mf.Objects = make([]manifestObject, 0, len(mf.objects))
+			for _, objectKey := range sortedKeys(mf.objects) {
+				mf.Objects = append(mf.Objects, manifestObject{Key: objectKey, Rows: mf.objects[objectKey]})
+				mf.Rows += mf.objects[objectKey]
+			}
+			body, err := json.MarshalIndent(mf, "", "  ")
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, File{Key: key, Body: body})
+		}
+	}
+	if successMarker && !m.failed {
+		for _, dir := range sortedKeys(m.dirs) {
+			files = append(files, File{Key: path.Join(dir, successMarkerFileName), Body: []byte{}})
+		}
+	}
+	return files, nil
+}
+
+func manifestColumns(table *schema.Table) []manifestColumn {
+	columns := make([]manifestColumn, len(table.Columns))
+	for i, column := range table.Columns {
+		columns[i] = manifestColumn{
+			Name:       column.Name,
+			Type:       column.Type.String(),
+			PrimaryKey: column.CreationOptions.PrimaryKey,
+			NotNull:    column.CreationOptions.NotNull,
+			Unique:     column.CreationOptions.Unique,
+		}
+	}
+	return columns
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SyncBatch is the part of a batch that was synced from the same source in the same sync
+type SyncBatch struct {
+	Source string
+	SyncID string
+	Data   [][]any
+}
+
+// SplitBySync splits the batch by the _cq_source_name and _cq_sync_time of the resources, so that every part is
+// written to the object of its {{SOURCE}} and {{SYNC_ID}}. If the path has neither variable, the batch is written
+// to a single object, and the source and sync ID of its first resource are used for the manifest.
+func SplitBySync(specPath string, table *schema.Table, data [][]any) []*SyncBatch {
+	split := strings.Contains(specPath, pathVarSource) || strings.Contains(specPath, pathVarSyncID)
+	sourceIndex := table.Columns.Index(schema.CqSourceNameColumn.Name)
+	syncTimeIndex := table.Columns.Index(schema.CqSyncTimeColumn.Name)
+	var batches []*SyncBatch
+	byKey := make(map[[2]string]*SyncBatch)
+	for _, resource := range data {
+		if !split && len(batches) > 0 {
+			batches[0].Data = append(batches[0].Data, resource)
+			continue
+		}
+		var source, syncID string
+		if sourceIndex != -1 {
+			source = sourceName(resource[sourceIndex])
+		}
+		if syncTimeIndex != -1 {
+			syncID = syncIDFromTime(resource[syncTimeIndex])
+		}
+		key := [2]string{source, syncID}
+		batch := byKey[key]
+		if batch == nil {
+			batch = &SyncBatch{Source: source, SyncID: syncID}
+			byKey[key] = batch
+			batches = append(batches, batch)
+		}
+		batch.Data = append(batch.Data, resource)
+	}
+	return batches
+}
+
+// sourceName returns the source name of a transformed _cq_source_name value. CSV and JSON keep the value as is,
+// while parquet transforms it to a string.
+func sourceName(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case *schema.Text:
+		if v.Status == schema.Present {
+			return v.Str
+		}
+	}
+	return ""
+}
+
+// syncIDFromTime returns the sync ID of a transformed _cq_sync_time value. CSV and JSON keep the value as is,
+// while parquet transforms it to milliseconds since the epoch.
+func syncIDFromTime(v any) string {
+	switch v := v.(type) {
+	case int64:
+		return time.UnixMilli(v).UTC().Format(syncIDLayout)
+	case time.Time:
+		return v.UTC().Format(syncIDLayout)
+	case *schema.Timestamptz:
+		if v.Status == schema.Present {
+			return v.Time.UTC().Format(syncIDLayout)
+		}
+	}
+	return ""
+}
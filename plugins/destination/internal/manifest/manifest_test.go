@@ -0,0 +1,149 @@
+package manifest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testManifestTable = &schema.Table{
+	Name: "test_table",
+	Columns: schema.ColumnList{
+		schema.CqSourceNameColumn,
+		schema.CqSyncTimeColumn,
+		{Name: "id", Type: schema.TypeInt, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+	},
+}
+
+func TestSplitBySync(t *testing.T) {
+	syncTime := time.Date(2021, 3, 5, 4, 1, 2, 3, time.UTC)
+	data := [][]any{
+		{"source-a", syncTime.UnixMilli(), int64(1)},
+		{&schema.Text{Str: "source-b", Status: schema.Present}, &schema.Timestamptz{Time: syncTime, Status: schema.Present}, int64(2)},
+		{"source-a", syncTime.UnixMilli(), int64(3)},
+	}
+
+	batches := SplitBySync("test/{{SOURCE}}/{{SYNC_ID}}/{{TABLE}}.json", testManifestTable, data)
+	if len(batches) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(batches))
+	}
+	if diff := cmp.Diff([]string{"source-a", "20210305T040102Z", "source-b", "20210305T040102Z"},
+		[]string{batches[0].Source, batches[0].SyncID, batches[1].Source, batches[1].SyncID}); diff != "" {
+		t.Errorf("unexpected batches (-want +got):\n%s", diff)
+	}
+	if len(batches[0].Data) != 2 || len(batches[1].Data) != 1 {
+		t.Errorf("unexpected batch sizes %d and %d", len(batches[0].Data), len(batches[1].Data))
+	}
+
+	batches = SplitBySync("test/{{TABLE}}.json", testManifestTable, data)
+	if len(batches) != 1 || len(batches[0].Data) != 3 || batches[0].Source != "source-a" {
+		t.Errorf("expected a single batch of source-a, got %d batches", len(batches))
+	}
+}
+
+func TestManifests(t *testing.T) {
+	cases := []struct {
+		specPath string
+		objects  []string
+		want     []string
+	}{
+		{
+			specPath: "test/{{TABLE}}/{{UUID}}.json",
+			objects:  []string{"test/test_table/a.json", "test/test_table/b.json"},
+			want:     []string{"test/test_table/_manifest.json", "test/test_table/_SUCCESS"},
+		},
+		{
+			specPath: "test/{{TABLE}}.json.{{UUID}}",
+			objects:  []string{"test/test_table.json.a", "test/test_table.json.b"},
+			want:     []string{"test/_test_table_manifest.json", "test/_SUCCESS"},
+		},
+		{
+			specPath: "test/{{TABLE}}/dt={{YEAR}}{{MONTH}}{{DAY}}/{{UUID}}.json",
+			objects:  []string{"test/test_table/dt=20210305/a.json", "test/test_table/dt=20210306/b.json"},
+			want: []string{
+				"test/test_table/dt=20210305/_manifest.json",
+				"test/test_table/dt=20210306/_manifest.json",
+				"test/test_table/dt=20210305/_SUCCESS",
+				"test/test_table/dt=20210306/_SUCCESS",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		m := New(tc.specPath, "json")
+		for _, object := range tc.objects {
+			m.Add(object, testManifestTable, "test-source", "20210305T040102Z", 2)
+		}
+		files, err := m.Files(true, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := make([]string, len(files))
+		for i, f := range files {
+			keys[i] = f.Key
+		}
+		if diff := cmp.Diff(tc.want, keys); diff != "" {
+			t.Errorf("unexpected files for %s (-want +got):\n%s", tc.specPath, diff)
+		}
+	}
+}
+
+func TestManifestsWriteFailed(t *testing.T) {
+	m := New("test/{{TABLE}}/{{UUID}}.json", "json")
+	m.Add("test/test_table/a.json", testManifestTable, "test-source", "20210305T040102Z", 2)
+	m.WriteFailed()
+
+	files, err := m.Files(true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, len(files))
+	for i, f := range files {
+		keys[i] = f.Key
+	}
+	if diff := cmp.Diff([]string{"test/test_table/_manifest.json"}, keys); diff != "" {
+		t.Errorf("unexpected files (-want +got):\n%s", diff)
+	}
+}
+
+func TestManifestContent(t *testing.T) {
+	m := New("test/{{TABLE}}/{{UUID}}.json", "json")
+	m.Add("test/test_table/b.json", testManifestTable, "test-source", "20210305T040102Z", 3)
+	m.Add("test/test_table/a.json", testManifestTable, "test-source", "20210305T040102Z", 2)
+	// written again with no_rotate
+	m.Add("test/test_table/a.json", testManifestTable, "test-source", "20210305T040102Z", 4)
+
+	files, err := m.Files(true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected a single manifest, got %d files", len(files))
+	}
+	var got manifest
+	if err := json.Unmarshal(files[0].Body, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := manifest{
+		Table:  "test_table",
+		Source: "test-source",
+		SyncID: "20210305T040102Z",
+		Format: "json",
+		Rows:   7,
+		Objects: []manifestObject{
+			{Key: "test/test_table/a.json", Rows: 4},
+			{Key: "test/test_table/b.json", Rows: 3},
+		},
+		Columns: []manifestColumn{
+			{Name: "_cq_source_name", Type: "TypeString"},
+			{Name: "_cq_sync_time", Type: "TypeTimestamp"},
+			{Name: "id", Type: "TypeInt", PrimaryKey: true},
+		},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(manifest{})); diff != "" {
+		t.Errorf("unexpected manifest (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/Shopify/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// configureAuth sets up TLS and SASL in the sarama config
+func (c *Client) configureAuth() error {
+	if *c.pluginSpec.TLSEnabled {
+		tlsConfig, err := c.pluginSpec.tlsConfig()
+		if err != nil {
+			return err
+		}
+		c.conf.Net.TLS.Enable = true
+		c.conf.Net.TLS.Config = tlsConfig
+	}
+	if c.pluginSpec.SaslUsername == "" {
+		return nil
+	}
+	c.conf.Net.SASL.Enable = true
+	c.conf.Net.SASL.User = c.pluginSpec.SaslUsername
+	c.conf.Net.SASL.Password = c.pluginSpec.SaslPassword
+	c.conf.Net.SASL.Handshake = true
+	switch c.pluginSpec.SaslMechanism {
+	case SaslMechanismOptionScramSHA256:
+		c.conf.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		c.conf.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{HashGeneratorFcn: sha256.New} }
+	case SaslMechanismOptionScramSHA512:
+		c.conf.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		c.conf.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{HashGeneratorFcn: sha512.New} }
+	default:
+		c.conf.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	}
+	return nil
+}
+
+func (s *Spec) tlsConfig() (*tls.Config, error) {
+	// nolint:gosec // verifying the certificates of the brokers can be disabled on purpose
+	config := &tls.Config{InsecureSkipVerify: *s.TLSInsecureSkipVerify}
+	if s.TLSCACert != "" {
+		pem, err := os.ReadFile(s.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls_ca_cert: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to parse tls_ca_cert %s", s.TLSCACert)
+		}
+	}
+	if s.TLSClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(s.TLSClientCert, s.TLSClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls_client_cert and tls_client_key: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// scramClient implements sarama.SCRAMClient
+type scramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func (x *scramClient) Begin(userName, password, authzID string) (err error) {
+	x.Client, err = x.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	x.ClientConversation = x.Client.NewConversation()
+	return nil
+}
+
+func (x *scramClient) Step(challenge string) (response string, err error) {
+	return x.ClientConversation.Step(challenge)
+}
+
+func (x *scramClient) Done() bool {
+	return x.ClientConversation.Done()
+}
@@ -2,7 +2,6 @@ package client
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"time"
 
@@ -29,9 +28,6 @@ type Client struct {
 }
 
 func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (destination.Client, error) {
-	if spec.WriteMode != specs.WriteModeAppend {
-		return nil, fmt.Errorf("destination only supports append mode")
-	}
 	c := &Client{
 		logger: logger.With().Str("module", "dest-kafka").Logger(),
 	}
@@ -40,10 +36,16 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 	if err := spec.UnmarshalSpec(&c.pluginSpec); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal spec: %w", err)
 	}
+	if c.pluginSpec.MessageKey == "" && spec.WriteMode != specs.WriteModeAppend {
+		c.pluginSpec.MessageKey = MessageKeyOptionPrimaryKey
+	}
 	c.pluginSpec.SetDefaults()
 	if err := c.pluginSpec.Validate(); err != nil {
 		return nil, err
 	}
+	if spec.WriteMode != specs.WriteModeAppend && c.pluginSpec.MessageKey != MessageKeyOptionPrimaryKey {
+		return nil, fmt.Errorf("message_key should be %s in %s mode", MessageKeyOptionPrimaryKey, spec.WriteMode)
+	}
 	if c.pluginSpec.Verbose {
 		sarama.Logger = NewSaramaLoggerAdapter(logger)
 	}
@@ -61,13 +63,8 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 	c.conf.Metadata.Full = true
 	c.conf.ClientID = "cq-destination-kafka-" + c.spec.Name
 
-	if c.pluginSpec.SaslUsername != "" {
-		c.conf.Net.SASL.Enable = true
-		c.conf.Net.SASL.User = c.pluginSpec.SaslUsername
-		c.conf.Net.SASL.Password = c.pluginSpec.SaslPassword
-		c.conf.Net.TLS.Enable = true
-		c.conf.Net.TLS.Config = &tls.Config{InsecureSkipVerify: true}
-		c.conf.Net.SASL.Handshake = true
+	if err := c.configureAuth(); err != nil {
+		return nil, err
 	}
 
 	var err error
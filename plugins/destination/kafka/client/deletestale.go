@@ -2,12 +2,244 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"sync/atomic"
 	"time"
 
+	"github.com/Shopify/sarama"
 	"github.com/cloudquery/plugin-sdk/schema"
 )
 
-func (*Client) DeleteStale(context.Context, schema.Tables, string, time.Time) error {
-	return fmt.Errorf("not implemented. plugin supports only 'append' mode")
+// keyIndexEntry is the source name and sync time of the last message of a key
+type keyIndexEntry struct {
+	sourceName string
+	syncTime   time.Time
+}
+
+// keyIndex holds the last message of every key of a topic. As messages are keyed by primary key, it holds the
+// resources currently in the topic, which is what a log-compacted topic keeps as well.
+type keyIndex map[string]keyIndexEntry
+
+// add updates the index with a message of the topic. Tombstones remove the key, and messages without the headers
+// (written by an older version of the plugin) are never considered stale.
+func (k keyIndex) add(msg *sarama.ConsumerMessage) {
+	if msg.Key == nil {
+		return
+	}
+	key := string(msg.Key)
+	if msg.Value == nil {
+		delete(k, key)
+		return
+	}
+	var entry keyIndexEntry
+	for _, header := range msg.Headers {
+		switch string(header.Key) {
+		case headerSourceName:
+			entry.sourceName = string(header.Value)
+		case headerSyncTime:
+			entry.syncTime, _ = time.Parse(time.RFC3339Nano, string(header.Value))
+		}
+	}
+	if entry.sourceName == "" || entry.syncTime.IsZero() {
+		delete(k, key)
+		return
+	}
+	k[key] = entry
+}
+
+// stale returns the sorted keys last written by the source in a sync before syncTime
+func (k keyIndex) stale(sourceName string, syncTime time.Time) []string {
+	// parquet keeps the sync time in milliseconds
+	syncTime = syncTime.Truncate(time.Millisecond)
+	var keys []string
+	for key, entry := range k {
+		if entry.sourceName == sourceName && entry.syncTime.Before(syncTime) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DeleteStale writes a tombstone for every resource of the source that was written in a previous sync but not in
+// the current one. The resources are found by consuming the topics of the tables up to their current offsets.
+func (c *Client) DeleteStale(ctx context.Context, tables schema.Tables, sourceName string, syncTime time.Time) error {
+	client, err := sarama.NewClient(c.pluginSpec.Brokers, c.conf)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	for _, table := range tables.FlattenTables() {
+		topic := c.pluginSpec.topic(table.Name)
+		index, err := c.keyIndex(ctx, client, consumer, topic)
+		if err != nil {
+			return fmt.Errorf("failed to index keys of topic %s: %w", topic, err)
+		}
+		keys := index.stale(sourceName, syncTime)
+		if len(keys) == 0 {
+			continue
+		}
+		c.logger.Info().Str("topic", topic).Int("tombstones", len(keys)).Msg("Writing tombstones for stale resources")
+		headers := []sarama.RecordHeader{
+			{Key: []byte(headerTable), Value: []byte(table.Name)},
+			{Key: []byte(headerSourceName), Value: []byte(sourceName)},
+			{Key: []byte(headerSyncTime), Value: []byte(syncTime.UTC().Format(time.RFC3339Nano))},
+		}
+		messages := make([]*sarama.ProducerMessage, 0, c.spec.BatchSize)
+		for i, key := range keys {
+			messages = append(messages, &sarama.ProducerMessage{
+				Topic:   topic,
+				Key:     sarama.StringEncoder(key),
+				Headers: headers,
+			})
+			if len(messages) >= c.spec.BatchSize || i == len(keys)-1 {
+				if err := c.producer.SendMessages(messages); err != nil {
+					return err
+				}
+				atomic.AddUint64(&c.metrics.Writes, uint64(len(messages)))
+				messages = make([]*sarama.ProducerMessage, 0, c.spec.BatchSize)
+			}
+		}
+	}
+	return nil
+}
+
+// keyIndex consumes all the partitions of the topic up to their current offsets
+func (c *Client) keyIndex(ctx context.Context, client sarama.Client, consumer sarama.Consumer, topic string) (keyIndex, error) {
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return nil, err
+	}
+	index := make(keyIndex)
+	for _, partition := range partitions {
+		if err := c.indexPartition(ctx, client, consumer, topic, partition, index); err != nil {
+			return nil, fmt.Errorf("partition %d: %w", partition, err)
+		}
+	}
+	return index, nil
+}
+
+// indexPartition adds the messages of the partition up to its high water mark to the index
+func (c *Client) indexPartition(ctx context.Context, client sarama.Client, consumer sarama.Consumer, topic string, partition int32, index keyIndex) error {
+	oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return err
+	}
+	partitionConsumer, err := consumer.ConsumePartition(topic, partition, oldest)
+	if err != nil {
+		return err
+	}
+	defer partitionConsumer.Close()
+	r := &partitionReader{
+		messages: partitionConsumer,
+		oldest:   oldest,
+		// the high water mark is the offset of the next message written to the partition when consuming starts
+		newest:   partitionConsumer.HighWaterMarkOffset(),
+		idleWait: maxWaitTime,
+		timeout:  indexTimeout,
+		onlyControlRecords: func(from, to int64) (bool, error) {
+			return onlyControlRecords(client, topic, partition, from, to, c.conf.Consumer.Fetch.Default)
+		},
+	}
+	return r.read(ctx, index.add)
+}
+
+// partitionMessages is the part of sarama.PartitionConsumer used to read a partition
+type partitionMessages interface {
+	Messages() <-chan *sarama.ConsumerMessage
+	Errors() <-chan *sarama.ConsumerError
+}
+
+// partitionReader reads the messages of a partition from oldest up to (excluding) newest
+type partitionReader struct {
+	messages partitionMessages
+	oldest   int64
+	newest   int64
+	// idleWait is the time without messages after which the offsets left are checked for transaction markers
+	idleWait time.Duration
+	// timeout is the time after which reading fails if newest wasn't reached
+	timeout time.Duration
+	// onlyControlRecords reports whether the offsets from up to (excluding) to hold only transaction markers
+	onlyControlRecords func(from, to int64) (bool, error)
+}
+
+// read calls fn with the messages of the partition. A partial index would make resources that were written again
+// look stale, so it fails unless every message up to newest was read.
+func (r *partitionReader) read(ctx context.Context, fn func(*sarama.ConsumerMessage)) error {
+	timeout := time.NewTimer(r.timeout)
+	defer timeout.Stop()
+	next := r.oldest
+	for next < r.newest {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-r.messages.Messages():
+			fn(msg)
+			next = msg.Offset + 1
+		case err := <-r.messages.Errors():
+			return err.Err
+		case <-time.After(r.idleWait):
+			// transaction markers take offsets but are never delivered, so the last offsets can't be waited for
+			done, err := r.onlyControlRecords(next, r.newest)
+			if err != nil {
+				return fmt.Errorf("failed to fetch offset %d: %w", next, err)
+			}
+			if done {
+				return nil
+			}
+		case <-timeout.C:
+			return fmt.Errorf("timed out reading offset %d of %d", next, r.newest)
+		}
+	}
+	return nil
+}
+
+// onlyControlRecords fetches the partition from its leader and reports whether the offsets from up to (excluding) to
+// are all control records, such as transaction markers
+func onlyControlRecords(client sarama.Client, topic string, partition int32, from, to int64, maxBytes int32) (bool, error) {
+	broker, err := client.Leader(topic, partition)
+	if err != nil {
+		return false, err
+	}
+	// version 4 is the first with record batches, which carry the control flag
+	req := &sarama.FetchRequest{Version: 4, MaxWaitTime: int32(maxWaitTime / time.Millisecond), MinBytes: 1}
+	req.AddBlock(topic, partition, from, maxBytes)
+	res, err := broker.Fetch(req)
+	if err != nil {
+		return false, err
+	}
+	block := res.GetBlock(topic, partition)
+	if block == nil {
+		return false, sarama.ErrIncompleteResponse
+	}
+	if !errors.Is(block.Err, sarama.ErrNoError) {
+		return false, block.Err
+	}
+	end := from
+	for _, records := range block.RecordsSet {
+		batch := records.RecordBatch
+		if batch == nil {
+			// legacy message sets have no control records
+			return false, nil
+		}
+		if batch.LastOffset() < from {
+			continue
+		}
+		if batch.FirstOffset >= to {
+			break
+		}
+		if !batch.Control {
+			return false, nil
+		}
+		end = batch.LastOffset() + 1
+	}
+	return end >= to, nil
 }
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/stretchr/testify/require"
+)
+
+func testConsumerMessage(key, sourceName string, syncTime time.Time, tombstone bool) *sarama.ConsumerMessage {
+	msg := &sarama.ConsumerMessage{Key: []byte(key)}
+	if !tombstone {
+		msg.Value = []byte(`{}`)
+	}
+	if sourceName != "" {
+		msg.Headers = []*sarama.RecordHeader{
+			{Key: []byte(headerSourceName), Value: []byte(sourceName)},
+			{Key: []byte(headerSyncTime), Value: []byte(syncTime.Format(time.RFC3339Nano))},
+		}
+	}
+	return msg
+}
+
+func TestKeyIndexStale(t *testing.T) {
+	previousSync := time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+	currentSync := time.Date(2023, 4, 2, 10, 0, 0, 123456789, time.UTC)
+
+	index := make(keyIndex)
+	for _, msg := range []*sarama.ConsumerMessage{
+		testConsumerMessage("deleted", "test_source", previousSync, false),
+		testConsumerMessage("updated", "test_source", previousSync, false),
+		testConsumerMessage("updated", "test_source", currentSync.Truncate(time.Millisecond), false), // parquet
+		testConsumerMessage("current", "test_source", currentSync, false),
+		testConsumerMessage("tombstoned", "test_source", previousSync, false),
+		testConsumerMessage("tombstoned", "test_source", previousSync, true),
+		testConsumerMessage("other_source", "other_source", previousSync, false),
+		testConsumerMessage("no_headers", "test_source", previousSync, false),
+		testConsumerMessage("no_headers", "", time.Time{}, false),
+		testConsumerMessage("also_deleted", "test_source", previousSync, false),
+	} {
+		index.add(msg)
+	}
+	index.add(&sarama.ConsumerMessage{Value: []byte(`{}`)}) // no key
+
+	require.Equal(t, []string{"also_deleted", "deleted"}, index.stale("test_source", currentSync))
+}
+
+type testPartitionMessages struct {
+	messages chan *sarama.ConsumerMessage
+	errors   chan *sarama.ConsumerError
+}
+
+func (p *testPartitionMessages) Messages() <-chan *sarama.ConsumerMessage { return p.messages }
+
+func (p *testPartitionMessages) Errors() <-chan *sarama.ConsumerError { return p.errors }
+
+func newTestPartitionReader(newest int64, onlyControlRecords func(from, to int64) (bool, error)) (*partitionReader, chan *sarama.ConsumerMessage) {
+	messages := make(chan *sarama.ConsumerMessage)
+	return &partitionReader{
+		messages:           &testPartitionMessages{messages: messages, errors: make(chan *sarama.ConsumerError)},
+		newest:             newest,
+		idleWait:           10 * time.Millisecond,
+		timeout:            time.Second,
+		onlyControlRecords: onlyControlRecords,
+	}, messages
+}
+
+func TestPartitionReaderWaitsAfterGap(t *testing.T) {
+	previousSync := time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+	currentSync := time.Date(2023, 4, 2, 10, 0, 0, 0, time.UTC)
+	r, messages := newTestPartitionReader(2, func(from, to int64) (bool, error) {
+		require.Equal(t, int64(1), from)
+		require.Equal(t, int64(2), to)
+		return false, nil
+	})
+	go func() {
+		messages <- testConsumerMessage("key", "test_source", previousSync, false)
+		time.Sleep(100 * time.Millisecond)
+		msg := testConsumerMessage("key", "test_source", currentSync, false)
+		msg.Offset = 1
+		messages <- msg
+	}()
+
+	index := make(keyIndex)
+	require.NoError(t, r.read(context.Background(), index.add))
+	require.Empty(t, index.stale("test_source", currentSync))
+}
+
+func TestPartitionReaderTransactionMarkers(t *testing.T) {
+	r, messages := newTestPartitionReader(3, func(from, to int64) (bool, error) {
+		return from == 1 && to == 3, nil
+	})
+	go func() {
+		messages <- testConsumerMessage("key", "test_source", time.Now(), false)
+	}()
+
+	index := make(keyIndex)
+	require.NoError(t, r.read(context.Background(), index.add))
+	require.Len(t, index, 1)
+}
+
+func TestPartitionReaderTimeout(t *testing.T) {
+	r, _ := newTestPartitionReader(1, func(from, to int64) (bool, error) {
+		return false, nil
+	})
+	r.timeout = 50 * time.Millisecond
+
+	require.ErrorContains(t, r.read(context.Background(), keyIndex{}.add), "timed out reading offset 0 of 1")
+}
+
+func TestTopicConfigEntries(t *testing.T) {
+	require.Nil(t, topicConfigEntries(specs.WriteModeAppend, nil))
+
+	entries := topicConfigEntries(specs.WriteModeOverwriteDeleteStale, map[string]string{"retention.ms": "1000"})
+	require.Len(t, entries, 2)
+	require.Equal(t, "compact", *entries["cleanup.policy"])
+	require.Equal(t, "1000", *entries["retention.ms"])
+
+	entries = topicConfigEntries(specs.WriteModeOverwrite, map[string]string{"cleanup.policy": "compact,delete"})
+	require.Equal(t, "compact,delete", *entries["cleanup.policy"])
+}
@@ -11,6 +11,8 @@ import (
 
 const (
 	maxWaitTime = 3 * time.Second
+	// indexTimeout bounds the time to read a partition when indexing the keys of a topic
+	indexTimeout = 5 * time.Minute
 )
 
 func (c *Client) Read(ctx context.Context, table *schema.Table, sourceName string, res chan<- []any) error {
@@ -19,20 +21,62 @@ func (c *Client) Read(ctx context.Context, table *schema.Table, sourceName strin
 		return err
 	}
 	defer consumer.Close()
-	partitionConsumer, err := consumer.ConsumePartition(table.Name, 0, sarama.OffsetOldest)
+
+	topic := c.pluginSpec.topic(table.Name)
+	partitions, err := consumer.Partitions(topic)
 	if err != nil {
 		return err
 	}
-	defer partitionConsumer.Close()
+	// the messages and errors of all the partitions are forwarded until done is closed
+	messages := make(chan *sarama.ConsumerMessage)
+	errors := make(chan *sarama.ConsumerError)
+	done := make(chan struct{})
+	var partitionConsumers []sarama.PartitionConsumer
+	defer func() {
+		close(done)
+		for _, partitionConsumer := range partitionConsumers {
+			partitionConsumer.Close()
+		}
+	}()
+	for _, partition := range partitions {
+		partitionConsumer, err := consumer.ConsumePartition(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return err
+		}
+		partitionConsumers = append(partitionConsumers, partitionConsumer)
+		go func() {
+			for msg := range partitionConsumer.Messages() {
+				select {
+				case messages <- msg:
+				case <-done:
+					return
+				}
+			}
+		}()
+		go func() {
+			for err := range partitionConsumer.Errors() {
+				select {
+				case errors <- err:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case msg := <-partitionConsumer.Messages():
+		case msg := <-messages:
+			if msg.Value == nil {
+				// tombstone
+				continue
+			}
 			if err := c.Client.Read(bytes.NewReader(msg.Value), table, sourceName, res); err != nil {
 				return err
 			}
-		case err := <-partitionConsumer.Errors():
+		case err := <-errors:
 			return err.Err
 		case <-time.After(maxWaitTime):
 			return nil
@@ -2,15 +2,76 @@ package client
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/cloudquery/filetypes"
 )
 
+const (
+	TopicVarTable = "{{TABLE}}"
+
+	defaultTopicTemplate     = TopicVarTable
+	defaultNumPartitions     = 1
+	defaultReplicationFactor = 1
+)
+
+type MessageKeyOption string
+
+const (
+	MessageKeyOptionNone       MessageKeyOption = "none"
+	MessageKeyOptionPrimaryKey MessageKeyOption = "primary_key"
+)
+
+var messageKeyOptions = []MessageKeyOption{MessageKeyOptionNone, MessageKeyOptionPrimaryKey}
+
+func (m MessageKeyOption) Validate() error {
+	for _, option := range messageKeyOptions {
+		if option == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v is not a valid option for message key. Options are: %v", m, messageKeyOptions)
+}
+
+type SaslMechanismOption string
+
+const (
+	SaslMechanismOptionPlain       SaslMechanismOption = "PLAIN"
+	SaslMechanismOptionScramSHA256 SaslMechanismOption = "SCRAM-SHA-256"
+	SaslMechanismOptionScramSHA512 SaslMechanismOption = "SCRAM-SHA-512"
+)
+
+var saslMechanismOptions = []SaslMechanismOption{SaslMechanismOptionPlain, SaslMechanismOptionScramSHA256, SaslMechanismOptionScramSHA512}
+
+func (s SaslMechanismOption) Validate() error {
+	for _, option := range saslMechanismOptions {
+		if option == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v is not a valid option for SASL mechanism. Options are: %v", s, saslMechanismOptions)
+}
+
 type Spec struct {
-	Brokers      []string `json:"brokers,omitempty"`
-	Verbose      bool     `json:"verbose,omitempty"`
-	SaslUsername string   `json:"sasl_username,omitempty"`
-	SaslPassword string   `json:"sasl_password,omitempty"`
+	Brokers       []string            `json:"brokers,omitempty"`
+	Verbose       bool                `json:"verbose,omitempty"`
+	SaslUsername  string              `json:"sasl_username,omitempty"`
+	SaslPassword  string              `json:"sasl_password,omitempty"`
+	SaslMechanism SaslMechanismOption `json:"sasl_mechanism,omitempty"`
+
+	// TLS is enabled, without verifying the certificates of the brokers, by default when SASL is used
+	TLSEnabled            *bool  `json:"tls_enabled,omitempty"`
+	TLSInsecureSkipVerify *bool  `json:"tls_insecure_skip_verify,omitempty"`
+	TLSCACert             string `json:"tls_ca_cert,omitempty"`
+	TLSClientCert         string `json:"tls_client_cert,omitempty"`
+	TLSClientKey          string `json:"tls_client_key,omitempty"`
+
+	TopicTemplate     string            `json:"topic_template,omitempty"`
+	NumPartitions     int32             `json:"num_partitions,omitempty"`
+	ReplicationFactor int16             `json:"replication_factor,omitempty"`
+	TopicConfig       map[string]string `json:"topic_config,omitempty"`
+	MessageKey        MessageKeyOption  `json:"message_key,omitempty"`
+
 	// This is currently only used for testing to wait for
 	// kafka cluster to be ready in GitHub actions.
 	MaxMetadataRetries int `json:"max_metadata_retries,omitempty"`
@@ -22,7 +83,31 @@ func (s *Spec) SetDefaults() {
 		s.FileSpec = &filetypes.FileSpec{}
 	}
 	s.FileSpec.SetDefaults()
+	if s.SaslMechanism == "" {
+		s.SaslMechanism = SaslMechanismOptionPlain
+	}
+	if s.TLSEnabled == nil {
+		enabled := s.SaslUsername != ""
+		s.TLSEnabled = &enabled
+	}
+	if s.TLSInsecureSkipVerify == nil {
+		insecure := s.SaslUsername != "" && s.TLSCACert == ""
+		s.TLSInsecureSkipVerify = &insecure
+	}
+	if s.TopicTemplate == "" {
+		s.TopicTemplate = defaultTopicTemplate
+	}
+	if s.NumPartitions == 0 {
+		s.NumPartitions = defaultNumPartitions
+	}
+	if s.ReplicationFactor == 0 {
+		s.ReplicationFactor = defaultReplicationFactor
+	}
+	if s.MessageKey == "" {
+		s.MessageKey = MessageKeyOptionNone
+	}
 }
+
 func (s *Spec) Validate() error {
 	if len(s.Brokers) == 0 {
 		return fmt.Errorf("at least one broker is required")
@@ -30,6 +115,25 @@ func (s *Spec) Validate() error {
 	if s.Format == "" {
 		return fmt.Errorf("format is required")
 	}
+	if err := s.SaslMechanism.Validate(); err != nil {
+		return err
+	}
+	if (s.TLSClientCert == "") != (s.TLSClientKey == "") {
+		return fmt.Errorf("tls_client_cert and tls_client_key should be set together")
+	}
+	if !strings.Contains(s.TopicTemplate, TopicVarTable) {
+		return fmt.Errorf("topic_template should contain %s", TopicVarTable)
+	}
+	if s.NumPartitions < 0 {
+		return fmt.Errorf("num_partitions should be positive")
+	}
+	if s.ReplicationFactor < 0 {
+		return fmt.Errorf("replication_factor should be positive")
+	}
+	return s.MessageKey.Validate()
+}
 
-	return nil
+// topic returns the name of the topic of the table
+func (s *Spec) topic(table string) string {
+	return strings.ReplaceAll(s.TopicTemplate, TopicVarTable, table)
 }
@@ -4,12 +4,25 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/cloudquery/plugin-sdk/plugins/destination"
 	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/cloudquery/plugin-sdk/specs"
+)
+
+const (
+	headerSourceName = "cq_source_name"
+	headerSyncTime   = "cq_sync_time"
+	headerTable      = "cq_table"
+
+	topicConfigCleanupPolicy = "cleanup.policy"
+	cleanupPolicyCompact     = "compact"
 )
 
 func (c *Client) createTopics(_ context.Context, tables schema.Tables) error {
@@ -19,10 +32,12 @@ func (c *Client) createTopics(_ context.Context, tables schema.Tables) error {
 		return err
 	}
 	defer admin.Close()
+	configEntries := topicConfigEntries(c.spec.WriteMode, c.pluginSpec.TopicConfig)
 	for _, table := range tables.FlattenTables() {
-		err := admin.CreateTopic(table.Name, &sarama.TopicDetail{
-			NumPartitions:     1,
-			ReplicationFactor: 1,
+		err := admin.CreateTopic(c.pluginSpec.topic(table.Name), &sarama.TopicDetail{
+			NumPartitions:     c.pluginSpec.NumPartitions,
+			ReplicationFactor: c.pluginSpec.ReplicationFactor,
+			ConfigEntries:     configEntries,
 		}, false)
 		if err != nil {
 			if strings.Contains(err.Error(), "Topic with this name already exists") {
@@ -34,11 +49,28 @@ func (c *Client) createTopics(_ context.Context, tables schema.Tables) error {
 	return nil
 }
 
+// topicConfigEntries returns the config of the topics to create. Unless configured otherwise, topics are compacted
+// in overwrite modes, so that they keep only the last message (or tombstone) of every resource.
+func topicConfigEntries(writeMode specs.WriteMode, topicConfig map[string]string) map[string]*string {
+	configEntries := make(map[string]*string, len(topicConfig)+1)
+	for k := range topicConfig {
+		v := topicConfig[k]
+		configEntries[k] = &v
+	}
+	if _, ok := configEntries[topicConfigCleanupPolicy]; !ok && writeMode != specs.WriteModeAppend {
+		compact := cleanupPolicyCompact
+		configEntries[topicConfigCleanupPolicy] = &compact
+	}
+	if len(configEntries) == 0 {
+		return nil
+	}
+	return configEntries
+}
+
 func (c *Client) Write(ctx context.Context, tables schema.Tables, res <-chan *destination.ClientResource) error {
 	if err := c.createTopics(ctx, tables); err != nil {
 		return err
 	}
-
 	messages := make([]*sarama.ProducerMessage, 0, c.spec.BatchSize)
 	for r := range res {
 		var b bytes.Buffer
@@ -49,9 +81,10 @@ func (c *Client) Write(ctx context.Context, tables schema.Tables, res <-chan *de
 		}
 		w.Flush()
 		messages = append(messages, &sarama.ProducerMessage{
-			Topic: r.TableName,
-			Key:   nil,
-			Value: sarama.ByteEncoder(b.Bytes()),
+			Topic:   c.pluginSpec.topic(r.TableName),
+			Key:     c.messageKey(table, r.Data),
+			Headers: messageHeaders(table, r.Data),
+			Value:   sarama.ByteEncoder(b.Bytes()),
 		})
 		if len(messages) >= c.spec.BatchSize {
 			if err := c.producer.SendMessages(messages); err != nil {
@@ -61,13 +94,92 @@ func (c *Client) Write(ctx context.Context, tables schema.Tables, res <-chan *de
 			messages = make([]*sarama.ProducerMessage, 0, c.spec.BatchSize)
 		}
 	}
-
 	if len(messages) > 0 {
 		if err := c.producer.SendMessages(messages); err != nil {
 			return err
 		}
 		atomic.AddUint64(&c.metrics.Writes, uint64(len(messages)))
 	}
-
 	return nil
 }
+
+// messageKey returns the key of the message of the resource: the value of its primary key, or a JSON array of the
+// values if the primary key has more than one column. Tables without a primary key are keyed by _cq_id.
+func (c *Client) messageKey(table *schema.Table, data []any) sarama.Encoder {
+	if c.pluginSpec.MessageKey != MessageKeyOptionPrimaryKey {
+		return nil
+	}
+	key := primaryKey(table, data)
+	if key == nil {
+		return nil
+	}
+	return sarama.StringEncoder(*key)
+}
+
+func primaryKey(table *schema.Table, data []any) *string {
+	var values []string
+	for i, column := range table.Columns {
+		if column.CreationOptions.PrimaryKey {
+			values = append(values, valueString(data[i]))
+		}
+	}
+	if len(values) == 0 {
+		i := table.Columns.Index(schema.CqIDColumn.Name)
+		if i == -1 {
+			return nil
+		}
+		values = append(values, valueString(data[i]))
+	}
+	if len(values) == 1 {
+		return &values[0]
+	}
+	b, _ := json.Marshal(values)
+	key := string(b)
+	return &key
+}
+
+// messageHeaders returns the headers with the table, source name and sync time of the resource
+func messageHeaders(table *schema.Table, data []any) []sarama.RecordHeader {
+	headers := []sarama.RecordHeader{{Key: []byte(headerTable), Value: []byte(table.Name)}}
+	if i := table.Columns.Index(schema.CqSourceNameColumn.Name); i != -1 {
+		if sourceName := valueString(data[i]); sourceName != "" {
+			headers = append(headers, sarama.RecordHeader{Key: []byte(headerSourceName), Value: []byte(sourceName)})
+		}
+	}
+	if i := table.Columns.Index(schema.CqSyncTimeColumn.Name); i != -1 {
+		if syncTime, ok := timeValue(data[i]); ok {
+			headers = append(headers, sarama.RecordHeader{Key: []byte(headerSyncTime), Value: []byte(syncTime.Format(time.RFC3339Nano))})
+		}
+	}
+	return headers
+}
+
+// valueString returns the string of a value transformed by the format. CSV and JSON keep the values as CQ types,
+// while parquet transforms them to primitive values.
+func valueString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// timeValue returns the time of a transformed timestamp. Parquet transforms timestamps to milliseconds since the
+// epoch.
+func timeValue(v any) (time.Time, bool) {
+	switch v := v.(type) {
+	case *schema.Timestamptz:
+		return v.Time.UTC(), v.Status == schema.Present
+	case time.Time:
+		return v.UTC(), true
+	case int64:
+		return time.UnixMilli(v).UTC(), true
+	default:
+		return time.Time{}, false
+	}
+}
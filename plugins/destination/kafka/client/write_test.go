@@ -0,0 +1,135 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/cloudquery/filetypes"
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrimaryKey(t *testing.T) {
+	cases := []struct {
+		name  string
+		table *schema.Table
+		data  []any
+		want  *string
+	}{
+		{
+			name: "single",
+			table: &schema.Table{Columns: schema.ColumnList{
+				{Name: "id", Type: schema.TypeString, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+				{Name: "name", Type: schema.TypeString},
+			}},
+			data: []any{&schema.Text{Str: "a", Status: schema.Present}, &schema.Text{Str: "b", Status: schema.Present}},
+			want: stringPtr("a"),
+		},
+		{
+			name: "composite",
+			table: &schema.Table{Columns: schema.ColumnList{
+				{Name: "account", Type: schema.TypeString, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+				{Name: "id", Type: schema.TypeInt, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+			}},
+			data: []any{"a", int64(1)},
+			want: stringPtr(`["a","1"]`),
+		},
+		{
+			name: "cq_id",
+			table: &schema.Table{Columns: schema.ColumnList{
+				{Name: "name", Type: schema.TypeString},
+				schema.CqIDColumn,
+			}},
+			data: []any{"a", "0f1b4a8c-95c6-4bd1-bb8b-a9a3d9f1a1a3"},
+			want: stringPtr("0f1b4a8c-95c6-4bd1-bb8b-a9a3d9f1a1a3"),
+		},
+		{
+			name:  "none",
+			table: &schema.Table{Columns: schema.ColumnList{{Name: "name", Type: schema.TypeString}}},
+			data:  []any{"a"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, primaryKey(tc.table, tc.data))
+		})
+	}
+}
+
+func TestMessageKeyNone(t *testing.T) {
+	c := &Client{pluginSpec: Spec{MessageKey: MessageKeyOptionNone}}
+	table := &schema.Table{Columns: schema.ColumnList{
+		{Name: "id", Type: schema.TypeString, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+	}}
+	require.Nil(t, c.messageKey(table, []any{"a"}))
+}
+
+func TestMessageHeaders(t *testing.T) {
+	syncTime := time.Date(2023, 4, 1, 10, 30, 0, 0, time.UTC)
+	table := &schema.Table{Name: "test_table", Columns: schema.ColumnList{
+		schema.CqSourceNameColumn,
+		schema.CqSyncTimeColumn,
+		{Name: "name", Type: schema.TypeString},
+	}}
+	want := []sarama.RecordHeader{
+		{Key: []byte(headerTable), Value: []byte("test_table")},
+		{Key: []byte(headerSourceName), Value: []byte("test_source")},
+		{Key: []byte(headerSyncTime), Value: []byte("2023-04-01T10:30:00Z")},
+	}
+
+	// CSV and JSON
+	data := []any{
+		&schema.Text{Str: "test_source", Status: schema.Present},
+		&schema.Timestamptz{Time: syncTime, Status: schema.Present},
+		&schema.Text{Str: "a", Status: schema.Present},
+	}
+	require.Equal(t, want, messageHeaders(table, data))
+
+	// parquet
+	data = []any{"test_source", syncTime.UnixMilli(), "a"}
+	require.Equal(t, want, messageHeaders(table, data))
+}
+
+func TestSpecValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		spec    Spec
+		wantErr bool
+	}{
+		{name: "defaults", spec: Spec{Brokers: []string{"localhost:9092"}}},
+		{name: "topic template", spec: Spec{Brokers: []string{"localhost:9092"}, TopicTemplate: "cq_{{TABLE}}"}},
+		{name: "topic template without table", spec: Spec{Brokers: []string{"localhost:9092"}, TopicTemplate: "cq"}, wantErr: true},
+		{name: "sasl mechanism", spec: Spec{Brokers: []string{"localhost:9092"}, SaslMechanism: "SCRAM-SHA-1"}, wantErr: true},
+		{name: "message key", spec: Spec{Brokers: []string{"localhost:9092"}, MessageKey: "id"}, wantErr: true},
+		{name: "client cert without key", spec: Spec{Brokers: []string{"localhost:9092"}, TLSClientCert: "cert.pem"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.spec.FileSpec = &filetypes.FileSpec{Format: filetypes.FormatTypeJSON}
+			tc.spec.SetDefaults()
+			err := tc.spec.Validate()
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSpecDefaults(t *testing.T) {
+	spec := Spec{Brokers: []string{"localhost:9092"}, SaslUsername: "user", TopicTemplate: "cq_{{TABLE}}_v1"}
+	spec.SetDefaults()
+	require.Equal(t, "cq_test_table_v1", spec.topic("test_table"))
+	require.True(t, *spec.TLSEnabled)
+	require.True(t, *spec.TLSInsecureSkipVerify)
+	require.Equal(t, SaslMechanismOptionPlain, spec.SaslMechanism)
+	require.Equal(t, int32(1), spec.NumPartitions)
+	require.Equal(t, int16(1), spec.ReplicationFactor)
+	require.Equal(t, MessageKeyOptionNone, spec.MessageKey)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/cloudquery/plugin-sdk/plugins/destination"
 	"github.com/cloudquery/plugin-sdk/schema"
@@ -31,10 +30,6 @@ func (c *Client) Close(context.Context) error {
 	return nil
 }
 
-func (*Client) DeleteStale(context.Context, schema.Tables, string, time.Time) error {
-	return fmt.Errorf("DeleteStale not supported")
-}
-
 func (c *Client) verifyVersion() error {
 	version, err := c.Meilisearch.Version()
 	if err != nil {
@@ -56,16 +51,18 @@ func (c *Client) verifyVersion() error {
 		return fmt.Errorf("failed to parse minor version (%q): %w", parts[1], err)
 	}
 
-	const (
-		minMajor = 1
-		minMinor = 1
-	)
+	const minMajor = 1
+	minMinor := 1
+	if c.dstSpec.WriteMode == specs.WriteModeOverwriteDeleteStale {
+		// deleting documents by filter requires 1.2
+		minMinor = 2
+	}
 
 	if (major > minMajor) || (major == minMajor && minor >= minMinor) {
 		return nil
 	}
 
-	return fmt.Errorf("unsupported Meilisearch version %s (must be >= 1.1)", version.PkgVersion)
+	return fmt.Errorf("unsupported Meilisearch version %s (must be >= %d.%d)", version.PkgVersion, minMajor, minMinor)
 }
 
 func New(_ context.Context, logger zerolog.Logger, dstSpec specs.Destination) (destination.Client, error) {
@@ -73,7 +70,7 @@ func New(_ context.Context, logger zerolog.Logger, dstSpec specs.Destination) (d
 	switch dstSpec.WriteMode {
 	case specs.WriteModeAppend:
 		pkColumn = schema.CqIDColumn.Name
-	case specs.WriteModeOverwrite:
+	case specs.WriteModeOverwrite, specs.WriteModeOverwriteDeleteStale:
 		pkColumn = hashColumnName
 	default:
 		return nil, fmt.Errorf("%q write_mode is not supported", dstSpec.WriteMode)
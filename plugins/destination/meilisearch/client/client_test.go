@@ -38,7 +38,6 @@ func TestPlugin(t *testing.T) {
 		},
 		specs.Destination{Spec: getTestSpec()},
 		destination.PluginTestSuiteTests{
-			SkipDeleteStale:          true,
 			MigrateStrategyOverwrite: migrateStrategy,
 			MigrateStrategyAppend:    migrateStrategy,
 		},
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+)
+
+// DeleteStale deletes the documents of the source from previous syncs. Sync times are stored as strings, which
+// Meilisearch filters can't compare, so the documents of the source with a sync time other than the current one
+// are deleted. Both attributes are filterable, as configureIndex makes all the columns filterable.
+func (c *Client) DeleteStale(ctx context.Context, tables schema.Tables, sourceName string, syncTime time.Time) error {
+	filter := staleFilter(sourceName, c.TransformTimestamptz(&schema.Timestamptz{Time: syncTime, Status: schema.Present}).(string))
+	for _, table := range tables.FlattenTables() {
+		index := c.Meilisearch.Index(table.Name)
+		c.logger.Debug().Str("index", index.UID).Str("filter", filter).Msg("deleting stale documents")
+
+		taskInfo, err := index.DeleteDocumentsByFilter(filter)
+		if err != nil {
+			return err
+		}
+
+		if err := c.waitTask(ctx, taskInfo); err != nil {
+			return fmt.Errorf("failed to delete stale documents from index %q: %w", index.UID, err)
+		}
+	}
+	return nil
+}
+
+func staleFilter(sourceName string, syncTime string) string {
+	return schema.CqSourceNameColumn.Name + " = " + quoteFilterValue(sourceName) +
+		" AND " + schema.CqSyncTimeColumn.Name + " != " + quoteFilterValue(syncTime)
+}
+
+func quoteFilterValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
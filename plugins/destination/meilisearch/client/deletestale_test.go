@@ -0,0 +1,11 @@
+package client
+
+import "testing"
+
+func TestStaleFilter(t *testing.T) {
+	got := staleFilter(`test "source"`, "2023-04-01T10:00:00Z")
+	want := `_cq_source_name = "test \"source\"" AND _cq_sync_time != "2023-04-01T10:00:00Z"`
+	if got != want {
+		t.Errorf("expected filter %s, got %s", want, got)
+	}
+}
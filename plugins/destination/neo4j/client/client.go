@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/cloudquery/plugin-sdk/plugins/destination"
 	"github.com/cloudquery/plugin-sdk/specs"
@@ -17,13 +18,17 @@ type Client struct {
 	spec       specs.Destination
 	pluginSpec Spec
 	client     neo4j.DriverWithContext
+	// parents maps the tables that are relations of another table to their parent table
+	parents     map[string]string
+	parentsLock sync.RWMutex
 }
 
 func New(ctx context.Context, logger zerolog.Logger, destSpec specs.Destination) (destination.Client, error) {
 	var err error
 	c := &Client{
-		logger: logger.With().Str("module", "neo4j").Logger(),
-		spec:   destSpec,
+		logger:  logger.With().Str("module", "neo4j").Logger(),
+		spec:    destSpec,
+		parents: make(map[string]string),
 	}
 	var spec Spec
 	if err := destSpec.UnmarshalSpec(&spec); err != nil {
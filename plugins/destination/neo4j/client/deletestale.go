@@ -14,7 +14,7 @@ const deleteCypher = "MATCH (n:%s) WHERE n._cq_source_name = $cq_source_name AND
 func (c *Client) DeleteStale(ctx context.Context, tables schema.Tables, source string, syncTime time.Time) error {
 	session := c.LoggedSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close(ctx)
-	for _, table := range tables {
+	for _, table := range tables.FlattenTables() {
 		stmt := fmt.Sprintf(deleteCypher, table.Name)
 		if _, err := session.Run(ctx, stmt, map[string]any{"cq_source_name": source, "cq_sync_time": syncTime}); err != nil {
 			return err
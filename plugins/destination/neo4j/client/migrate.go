@@ -2,11 +2,57 @@ package client
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
-// Migrate tables. Like mongo, neo4j does not have a schema, so this is a no-op.
-func (*Client) Migrate(ctx context.Context, tables schema.Tables) error {
-	return nil
+// Migrate tables. Like mongo, neo4j does not have a schema, so only the constraints and indexes used to match
+// nodes when writing them and their relationships are created.
+func (c *Client) Migrate(ctx context.Context, tables schema.Tables) error {
+	session := c.LoggedSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+	for _, table := range tables.FlattenTables() {
+		c.registerRelations(table)
+		for _, stmt := range c.schemaStatements(table) {
+			c.logger.Debug().Str("stmt", stmt).Msg("Executing statement")
+			if _, err := session.Run(ctx, stmt, nil); err != nil {
+				return fmt.Errorf("failed to migrate table %s: %w", table.Name, err)
+			}
+		}
+	}
+	return session.Close(ctx)
+}
+
+// schemaStatements returns the statements creating the uniqueness constraints on the primary keys and _cq_id of
+// the table, and the index on _cq_parent_id used to find the children of a node.
+func (c *Client) schemaStatements(table *schema.Table) []string {
+	var stmts []string
+	pks := table.PrimaryKeys()
+	if len(pks) > 0 {
+		stmts = append(stmts, uniqueConstraint(table.Name, table.Name+"_cqpk", pks))
+	}
+	if table.Columns.Get(schema.CqIDColumn.Name) != nil && !(len(pks) == 1 && pks[0] == schema.CqIDColumn.Name) {
+		stmts = append(stmts, uniqueConstraint(table.Name, table.Name+"_cq_id", []string{schema.CqIDColumn.Name}))
+	}
+	if c.parent(table.Name) != "" && table.Columns.Get(schema.CqParentIDColumn.Name) != nil {
+		stmts = append(stmts, fmt.Sprintf("CREATE INDEX %s_cq_parent_id IF NOT EXISTS FOR (n:%s) ON (n.%s)", table.Name, table.Name, schema.CqParentIDColumn.Name))
+	}
+	return stmts
+}
+
+// uniqueConstraint returns the statement creating a uniqueness constraint. Constraints on more than one property
+// require Neo4j 5.
+func uniqueConstraint(label string, name string, properties []string) string {
+	props := make([]string, len(properties))
+	for i, property := range properties {
+		props[i] = "n." + property
+	}
+	require := props[0]
+	if len(props) > 1 {
+		require = "(" + strings.Join(props, ", ") + ")"
+	}
+	return fmt.Sprintf("CREATE CONSTRAINT %s IF NOT EXISTS FOR (n:%s) REQUIRE %s IS UNIQUE", name, label, require)
 }
@@ -2,13 +2,20 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/cloudquery/plugin-sdk/schema"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
+// childRelationship is the type of the relationships from the node of a resource to the nodes of its relations
+const childRelationship = "HAS_CHILD"
+
 func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, resources [][]any) error {
+	// the relations are registered before the nodes are written, and the relationships are written after them, so
+	// that a parent and a child written concurrently always have their relationship created by one of them
+	c.registerRelations(table)
 	session := c.LoggedSession(ctx, neo4j.SessionConfig{})
 	defer session.Close(ctx)
 	rows := make([]map[string]any, len(resources))
@@ -18,6 +25,21 @@ func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, resou
 			rows[i][column.Name] = resource[j]
 		}
 	}
+	stmts := append([]string{mergeNodes(table)}, c.relationshipStatements(table)...)
+	for _, stmt := range stmts {
+		c.logger.Debug().Str("stmt", stmt).Any("rows", rows).Msg("Executing statement")
+		if _, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			_, err := tx.Run(ctx, stmt, map[string]any{"rows": rows})
+			return nil, err
+		}); err != nil {
+			return err
+		}
+	}
+
+	return session.Close(ctx)
+}
+
+func mergeNodes(table *schema.Table) string {
 	var sb strings.Builder
 	sb.WriteString("UNWIND $rows AS row MERGE (t:")
 	sb.WriteString(table.Name)
@@ -36,14 +58,58 @@ func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, resou
 		sb.WriteString(column)
 	}
 	sb.WriteString("}) SET t = row")
-	stmt := sb.String()
-	c.logger.Debug().Str("stmt", stmt).Any("rows", rows).Msg("Executing statement")
-	if _, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		_, err := tx.Run(ctx, stmt, map[string]any{"rows": rows})
-		return nil, err
-	}); err != nil {
-		return err
+	return sb.String()
+}
+
+// registerRelations records the table as the parent of its relations
+func (c *Client) registerRelations(table *schema.Table) {
+	c.parentsLock.Lock()
+	defer c.parentsLock.Unlock()
+	for _, relation := range table.Relations {
+		c.parents[relation.Name] = table.Name
 	}
+}
 
-	return session.Close(ctx)
+// parent returns the parent table of the table, or an empty string if it's unknown
+func (c *Client) parent(table string) string {
+	c.parentsLock.RLock()
+	defer c.parentsLock.RUnlock()
+	return c.parents[table]
+}
+
+// relationshipStatements returns the statements creating the relationships between the rows of the table and the
+// nodes of their parent and children. Nodes are matched by _cq_id and _cq_parent_id.
+func (c *Client) relationshipStatements(table *schema.Table) []string {
+	if table.Columns.Get(schema.CqIDColumn.Name) == nil {
+		return nil
+	}
+	var stmts []string
+	if parent := c.parent(table.Name); parent != "" && table.Columns.Get(schema.CqParentIDColumn.Name) != nil {
+		stmts = append(stmts, linkParent(table.Name, parent))
+	}
+	for _, relation := range table.Relations {
+		if relation.Columns.Get(schema.CqParentIDColumn.Name) != nil {
+			stmts = append(stmts, linkChildren(table.Name, relation.Name))
+		}
+	}
+	return stmts
+}
+
+// linkParent returns the statement creating the relationship from the parent node of every row, and removing the
+// relationships from the nodes that are no longer its parent
+func linkParent(table string, parent string) string {
+	return fmt.Sprintf("UNWIND $rows AS row MATCH (c:%s {%s: row.%s}) MATCH (p:%s {%s: row.%s}) MERGE (p)-[:%s]->(c) "+
+		"WITH c, p OPTIONAL MATCH (other)-[old:%s]->(c) WHERE other <> p DELETE old",
+		table, schema.CqIDColumn.Name, schema.CqIDColumn.Name,
+		parent, schema.CqIDColumn.Name, schema.CqParentIDColumn.Name,
+		childRelationship, childRelationship)
+}
+
+// linkChildren returns the statement creating the relationships to the nodes of the relation that were written
+// before the rows
+func linkChildren(table string, relation string) string {
+	return fmt.Sprintf("UNWIND $rows AS row MATCH (p:%s {%s: row.%s}) MATCH (c:%s {%s: row.%s}) MERGE (p)-[:%s]->(c)",
+		table, schema.CqIDColumn.Name, schema.CqIDColumn.Name,
+		relation, schema.CqParentIDColumn.Name, schema.CqIDColumn.Name,
+		childRelationship)
 }
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelationshipStatements(t *testing.T) {
+	child := &schema.Table{
+		Name:    "test_child",
+		Columns: schema.ColumnList{schema.CqIDColumn, schema.CqParentIDColumn, {Name: "name", Type: schema.TypeString}},
+	}
+	parent := &schema.Table{
+		Name: "test_parent",
+		Columns: schema.ColumnList{
+			schema.CqIDColumn,
+			schema.CqParentIDColumn,
+			{Name: "account_id", Type: schema.TypeString, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+			{Name: "arn", Type: schema.TypeString, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+		},
+		Relations: schema.Tables{child},
+	}
+	c := &Client{parents: make(map[string]string)}
+
+	// the parent of the child is only known once the parent was seen
+	require.Empty(t, c.relationshipStatements(child))
+	c.registerRelations(parent)
+	require.Equal(t, "test_parent", c.parent("test_child"))
+
+	require.Equal(t, []string{
+		"UNWIND $rows AS row MATCH (p:test_parent {_cq_id: row._cq_id}) MATCH (c:test_child {_cq_parent_id: row._cq_id}) MERGE (p)-[:HAS_CHILD]->(c)",
+	}, c.relationshipStatements(parent))
+	require.Equal(t, []string{
+		"UNWIND $rows AS row MATCH (c:test_child {_cq_id: row._cq_id}) MATCH (p:test_parent {_cq_id: row._cq_parent_id}) MERGE (p)-[:HAS_CHILD]->(c) " +
+			"WITH c, p OPTIONAL MATCH (other)-[old:HAS_CHILD]->(c) WHERE other <> p DELETE old",
+	}, c.relationshipStatements(child))
+
+	require.Equal(t, []string{
+		"CREATE CONSTRAINT test_parent_cqpk IF NOT EXISTS FOR (n:test_parent) REQUIRE (n.account_id, n.arn) IS UNIQUE",
+		"CREATE CONSTRAINT test_parent_cq_id IF NOT EXISTS FOR (n:test_parent) REQUIRE n._cq_id IS UNIQUE",
+	}, c.schemaStatements(parent))
+	require.Equal(t, []string{
+		"CREATE CONSTRAINT test_child_cq_id IF NOT EXISTS FOR (n:test_child) REQUIRE n._cq_id IS UNIQUE",
+		"CREATE INDEX test_child_cq_parent_id IF NOT EXISTS FOR (n:test_child) ON (n._cq_parent_id)",
+	}, c.schemaStatements(child))
+}
@@ -3,7 +3,10 @@ package client
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/cloudquery/plugin-sdk/plugins/destination"
 	"github.com/cloudquery/plugin-sdk/specs"
@@ -21,11 +24,18 @@ type Client struct {
 	conn                *pgxpool.Pool
 	logger              zerolog.Logger
 	spec                specs.Destination
+	pgSpec              Spec
 	currentDatabaseName string
 	currentSchemaName   string
 	pgType              pgType
 	metrics             destination.Metrics
 	batchSize           int
+	// partitions are the partitions created or found to exist by this client
+	partitions     map[string]bool
+	partitionsLock sync.RWMutex
+	// migratePlanFile is set by `cloudquery migrate --dry-run`, which makes Migrate write the changes it would apply to
+	// this file instead of applying them
+	migratePlanFile string
 }
 
 type pgType int
@@ -38,7 +48,8 @@ const (
 
 func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (destination.Client, error) {
 	c := &Client{
-		logger: logger.With().Str("module", "pg-dest").Logger(),
+		logger:     logger.With().Str("module", "pg-dest").Logger(),
+		partitions: make(map[string]bool),
 	}
 	var specPostgreSql Spec
 	c.spec = spec
@@ -46,7 +57,19 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 		return nil, fmt.Errorf("failed to unmarshal postgresql spec: %w", err)
 	}
 	specPostgreSql.SetDefaults()
+	if err := specPostgreSql.Validate(); err != nil {
+		return nil, err
+	}
+	// the primary key of a partitioned table has to include the partition column, which would make every sync
+	// insert new rows instead of overwriting them
+	if specPostgreSql.TimePartitioning != TimePartitioningOptionNone && spec.WriteMode != specs.WriteModeAppend {
+		return nil, fmt.Errorf("time_partitioning requires write_mode append")
+	}
+	c.pgSpec = specPostgreSql
 	c.batchSize = spec.BatchSize
+	if dir := os.Getenv(migratePlanDirEnv); dir != "" {
+		c.migratePlanFile = filepath.Join(dir, spec.Name+".json")
+	}
 	logLevel, err := tracelog.LogLevelFromString(specPostgreSql.PgxLogLevel.String())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse pgx log level %s: %w", specPostgreSql.PgxLogLevel, err)
@@ -75,14 +98,24 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current database: %w", err)
 	}
-	c.currentSchemaName, err = c.currentSchema(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get current schema: %w", err)
+	if specPostgreSql.SchemaName != "" {
+		c.currentSchemaName = specPostgreSql.SchemaName
+	} else {
+		c.currentSchemaName, err = c.currentSchema(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current schema: %w", err)
+		}
 	}
 	c.pgType, err = c.getPgType(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get database type: %w", err)
 	}
+	if c.pgType == pgTypeCockroachDB && specPostgreSql.WriteMethod == WriteMethodCopyFrom {
+		return nil, fmt.Errorf("write_method %s is not supported with CockroachDB", WriteMethodCopyFrom)
+	}
+	if c.pgType == pgTypeCockroachDB && specPostgreSql.TimePartitioning != TimePartitioningOptionNone {
+		return nil, fmt.Errorf("time_partitioning is not supported with CockroachDB")
+	}
 	return c, nil
 }
 
@@ -117,6 +150,11 @@ func (c *Client) currentSchema(ctx context.Context) (string, error) {
 	return schema, nil
 }
 
+// tableIdentifier returns the schema qualified identifier of the table, with the table prefix
+func (c *Client) tableIdentifier(tableName string) pgx.Identifier {
+	return pgx.Identifier{c.currentSchemaName, c.pgSpec.TablePrefix + tableName}
+}
+
 func (c *Client) getPgType(ctx context.Context) (pgType, error) {
 	var version string
 	var typ pgType
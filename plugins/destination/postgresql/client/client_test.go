@@ -40,3 +40,40 @@ func TestPgPlugin(t *testing.T) {
 			MigrateStrategyAppend:    strategy,
 		})
 }
+
+func TestPgPluginCopyFrom(t *testing.T) {
+	destination.PluginTestSuiteRunner(t,
+		func() *destination.Plugin {
+			return destination.NewPlugin("postgresql", "development", New)
+		},
+		specs.Destination{
+			Spec: &Spec{
+				ConnectionString: getTestConnection(),
+				PgxLogLevel:      LogLevelTrace,
+				WriteMethod:      WriteMethodCopyFrom,
+			},
+		},
+		destination.PluginTestSuiteTests{
+			MigrateStrategyOverwrite: strategy,
+			MigrateStrategyAppend:    strategy,
+		})
+}
+
+func TestPgPluginSchemaAndTablePrefix(t *testing.T) {
+	destination.PluginTestSuiteRunner(t,
+		func() *destination.Plugin {
+			return destination.NewPlugin("postgresql", "development", New)
+		},
+		specs.Destination{
+			Spec: &Spec{
+				ConnectionString: getTestConnection(),
+				PgxLogLevel:      LogLevelTrace,
+				SchemaName:       "cq_test_schema",
+				TablePrefix:      "cq_",
+			},
+		},
+		destination.PluginTestSuiteTests{
+			MigrateStrategyOverwrite: strategy,
+			MigrateStrategyAppend:    strategy,
+		})
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/cloudquery/plugin-sdk/plugins/destination"
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	stagingTablePrefix = "_cq_staging_"
+	// copySeqColumn numbers the rows copied to a staging table, so that the last row wins when a batch has
+	// more than one row with the same primary key
+	copySeqColumn = "_cq_copy_seq"
+)
+
+// copyFrom writes the resources with COPY FROM, buffering them per table until there are batchSize resources.
+// In append mode, and for tables without a primary key, the resources are copied straight into the table. In
+// overwrite mode they are copied into a temporary staging table and merged into the table with a single
+// INSERT ... ON CONFLICT statement.
+func (c *Client) copyFrom(ctx context.Context, tables schema.Tables, res <-chan *destination.ClientResource) error {
+	rows := make(map[string][][]any)
+	buffered := 0
+	partitions := make(partitionCache)
+	flush := func() error {
+		for tableName, tableRows := range rows {
+			if err := c.copyTable(ctx, tables.Get(tableName), tableRows); err != nil {
+				return err
+			}
+			atomic.AddUint64(&c.metrics.Writes, uint64(len(tableRows)))
+		}
+		rows = make(map[string][][]any)
+		buffered = 0
+		return nil
+	}
+	for r := range res {
+		table := tables.Get(r.TableName)
+		if table == nil {
+			panic(fmt.Errorf("table %s not found", r.TableName))
+		}
+		if err := c.ensurePartition(ctx, partitions, table, r.Data); err != nil {
+			return err
+		}
+		rows[r.TableName] = append(rows[r.TableName], r.Data)
+		buffered++
+		if buffered >= c.batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+func (c *Client) copyTable(ctx context.Context, table *schema.Table, rows [][]any) error {
+	columns := table.Columns.Names()
+	if c.spec.WriteMode == specs.WriteModeAppend || len(table.PrimaryKeys()) == 0 {
+		if _, err := c.conn.CopyFrom(ctx, c.tableIdentifier(table.Name), columns, pgx.CopyFromRows(rows)); err != nil {
+			return batchErr(fmt.Sprintf("failed to copy to table %s", table.Name), err)
+		}
+		return nil
+	}
+
+	tx, err := c.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// rolling back a committed transaction is a no-op
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	staging := stagingTableName(table)
+	target := c.tableIdentifier(table.Name).Sanitize()
+	if _, err := tx.Exec(ctx, createStagingTable(target, staging)); err != nil {
+		return batchErr(fmt.Sprintf("failed to create staging table for %s", table.Name), err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{staging}, columns, pgx.CopyFromRows(rows)); err != nil {
+		return batchErr(fmt.Sprintf("failed to copy to staging table of %s", table.Name), err)
+	}
+	if _, err := tx.Exec(ctx, mergeStagingTable(table, target, staging)); err != nil {
+		return batchErr(fmt.Sprintf("failed to merge staging table into %s", table.Name), err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return batchErr(fmt.Sprintf("failed to commit copy to %s", table.Name), err)
+	}
+	return nil
+}
+
+// stagingTableName returns the name of the temporary table a batch of the table is copied to. Temporary tables
+// live in their own schema, so they can't conflict with the tables of the sync.
+func stagingTableName(table *schema.Table) string {
+	return stagingTablePrefix + table.Name
+}
+
+// createStagingTable returns the statement creating the staging table of the sanitized target table
+func createStagingTable(target string, staging string) string {
+	var sb strings.Builder
+	sb.WriteString("create temporary table ")
+	sb.WriteString(pgx.Identifier{staging}.Sanitize())
+	sb.WriteString(" (like ")
+	sb.WriteString(target)
+	sb.WriteString(", ")
+	sb.WriteString(pgx.Identifier{copySeqColumn}.Sanitize())
+	sb.WriteString(" bigserial) on commit drop")
+	return sb.String()
+}
+
+// mergeStagingTable returns the statement merging the staging table into the sanitized target table
+func mergeStagingTable(table *schema.Table, target string, staging string) string {
+	columns := sanitizedColumnNames(table.Columns.Names())
+	pks := sanitizedColumnNames(table.PrimaryKeys())
+	var sb strings.Builder
+	sb.WriteString("insert into ")
+	sb.WriteString(target)
+	sb.WriteString(" (")
+	sb.WriteString(columns)
+	sb.WriteString(") select distinct on (")
+	sb.WriteString(pks)
+	sb.WriteString(") ")
+	sb.WriteString(columns)
+	sb.WriteString(" from ")
+	sb.WriteString(pgx.Identifier{staging}.Sanitize())
+	sb.WriteString(" order by ")
+	sb.WriteString(pks)
+	sb.WriteString(", ")
+	sb.WriteString(pgx.Identifier{copySeqColumn}.Sanitize())
+	sb.WriteString(" desc")
+	sb.WriteString(onConflictUpdate(table))
+	return sb.String()
+}
+
+func sanitizedColumnNames(names []string) string {
+	sanitized := make([]string, len(names))
+	for i, name := range names {
+		sanitized[i] = pgx.Identifier{name}.Sanitize()
+	}
+	return strings.Join(sanitized, ",")
+}
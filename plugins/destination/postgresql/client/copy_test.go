@@ -0,0 +1,38 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStagingTableStatements(t *testing.T) {
+	table := &schema.Table{
+		Name:             "test_table",
+		PkConstraintName: "test_table_cqpk",
+		Columns: schema.ColumnList{
+			{Name: "id", Type: schema.TypeString, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+			{Name: "name", Type: schema.TypeString},
+		},
+	}
+	staging := stagingTableName(table)
+	target := `"public"."cq_test_table"`
+	require.Equal(t, "_cq_staging_test_table", staging)
+	require.Equal(t,
+		`create temporary table "_cq_staging_test_table" (like "public"."cq_test_table", "_cq_copy_seq" bigserial) on commit drop`,
+		createStagingTable(target, staging))
+	require.Equal(t,
+		`insert into "public"."cq_test_table" ("id","name") select distinct on ("id") "id","name" from "_cq_staging_test_table" order by "id", "_cq_copy_seq" desc on conflict on constraint test_table_cqpk do update set "id"=excluded."id","name"=excluded."name"`,
+		mergeStagingTable(table, target, staging))
+}
+
+func TestSpecValidate(t *testing.T) {
+	spec := Spec{}
+	spec.SetDefaults()
+	require.Equal(t, WriteMethodBatch, spec.WriteMethod)
+	require.NoError(t, spec.Validate())
+
+	spec.WriteMethod = "insert"
+	require.ErrorContains(t, spec.Validate(), `invalid write_method "insert"`)
+}
@@ -15,7 +15,7 @@ func (c *Client) DeleteStale(ctx context.Context, tables schema.Tables, source s
 	for _, table := range tables.FlattenTables() {
 		var sb strings.Builder
 		sb.WriteString("delete from ")
-		sb.WriteString(pgx.Identifier{table.Name}.Sanitize())
+		sb.WriteString(c.tableIdentifier(table.Name).Sanitize())
 		sb.WriteString(" where ")
 		sb.WriteString(schema.CqSourceNameColumn.Name)
 		sb.WriteString(" = $1 and ")
@@ -2,7 +2,10 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/cloudquery/plugin-sdk/schema"
@@ -50,7 +53,7 @@ FROM
 WHERE
 	pg_attribute.attnum > 0
 	AND NOT pg_attribute.attisdropped
-	AND pg_catalog.pg_namespace.nspname = '%s'
+	AND pg_catalog.pg_namespace.nspname = $1
 ORDER BY
 	table_name ASC , ordinal_position ASC;
 `
@@ -85,7 +88,7 @@ FROM
 WHERE
 	pg_attribute.attnum > 0
 	AND NOT pg_attribute.attisdropped
-	AND pg_catalog.pg_namespace.nspname = '%s'
+	AND pg_catalog.pg_namespace.nspname = $1
 	AND information_schema.columns.is_hidden != 'YES'
 ORDER BY
 	table_name ASC , ordinal_position ASC;
@@ -98,7 +101,7 @@ func (c *Client) listPgTables(ctx context.Context, pluginTables schema.Tables) (
 	if c.pgType == pgTypeCockroachDB {
 		sql = selectAllTablesCockroach
 	}
-	rows, err := c.conn.Query(ctx, fmt.Sprintf(sql, c.currentSchemaName))
+	rows, err := c.conn.Query(ctx, sql, c.currentSchemaName)
 	if err != nil {
 		return nil, err
 	}
@@ -110,6 +113,11 @@ func (c *Client) listPgTables(ctx context.Context, pluginTables schema.Tables) (
 		if err := rows.Scan(&ordinalPosition, &tableName, &columnName, &columnType, &isPrimaryKey, &notNull, &pkName); err != nil {
 			return nil, err
 		}
+		// tables are returned by the name used in the plugin, without the table prefix
+		if !strings.HasPrefix(tableName, c.pgSpec.TablePrefix) {
+			continue
+		}
+		tableName = strings.TrimPrefix(tableName, c.pgSpec.TablePrefix)
 		// We don't want to migrate tables that are not a part of the spec, or non CloudQuery tables
 		if pluginTables.Get(tableName) == nil {
 			continue
@@ -251,6 +259,92 @@ func (c *Client) nonAutoMigrableTables(tables schema.Tables, pgTables schema.Tab
 	return result, tableChanges
 }
 
+// tableMigrationPlan describes what Migrate would do to a single table
+type tableMigrationPlan struct {
+	Table          string   `json:"table"`
+	Action         string   `json:"action"`
+	AddColumns     []string `json:"add_columns,omitempty"`
+	RemoveColumns  []string `json:"remove_columns,omitempty"`
+	ChangeColumns  []string `json:"change_columns,omitempty"`
+	RequiresForced bool     `json:"requires_forced_migration"`
+}
+
+// migratePlanDirEnv is set by `cloudquery migrate --dry-run` to the directory the plan of every destination is written
+// to, in a file named after the destination
+const migratePlanDirEnv = "CQ_MIGRATE_PLAN_DIR"
+
+const (
+	migrationActionCreate   = "create"
+	migrationActionAlter    = "alter"
+	migrationActionRecreate = "recreate"
+	migrationActionNone     = "none"
+)
+
+// migrationPlan returns the changes Migrate would apply to every table, without touching the database
+func (c *Client) migrationPlan(tables schema.Tables, pgTables schema.Tables, repartitioned map[string]bool) []tableMigrationPlan {
+	plans := make([]tableMigrationPlan, 0, len(tables))
+	for _, table := range tables {
+		if len(table.Columns) == 0 {
+			continue
+		}
+		plan := tableMigrationPlan{Table: table.Name}
+		pgTable := pgTables.Get(table.Name)
+		if pgTable == nil {
+			plan.Action = migrationActionCreate
+			plans = append(plans, plan)
+			continue
+		}
+		changes := table.GetChanges(pgTable)
+		for _, change := range changes {
+			switch change.Type {
+			case schema.TableColumnChangeTypeAdd:
+				plan.AddColumns = append(plan.AddColumns, change.ColumnName)
+			case schema.TableColumnChangeTypeRemove:
+				plan.RemoveColumns = append(plan.RemoveColumns, change.ColumnName)
+			case schema.TableColumnChangeTypeUpdate:
+				plan.ChangeColumns = append(plan.ChangeColumns, change.ColumnName)
+			}
+		}
+		switch {
+		case !c.canAutoMigrate(changes) || repartitioned[table.Name]:
+			plan.Action = migrationActionRecreate
+			plan.RequiresForced = true
+		case len(plan.AddColumns) > 0:
+			plan.Action = migrationActionAlter
+		default:
+			plan.Action = migrationActionNone
+		}
+		plans = append(plans, plan)
+	}
+	return plans
+}
+
+func (c *Client) logMigrationPlan(plans []tableMigrationPlan) {
+	for _, plan := range plans {
+		c.logger.Info().
+			Str("table", plan.Table).
+			Str("action", plan.Action).
+			Strs("add_columns", plan.AddColumns).
+			Strs("remove_columns", plan.RemoveColumns).
+			Strs("change_columns", plan.ChangeColumns).
+			Bool("requires_forced_migration", plan.RequiresForced).
+			Str("migrate_mode", c.spec.MigrateMode.String()).
+			Msg("Migration plan (dry run)")
+	}
+}
+
+// writeMigrationPlan writes the plan as JSON to the file given by the CLI, which reports it to the user
+func writeMigrationPlan(path string, plans []tableMigrationPlan) error {
+	b, err := json.Marshal(plans)
+	if err != nil {
+		return fmt.Errorf("failed to marshal migration plan: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("failed to write migration plan: %w", err)
+	}
+	return nil
+}
+
 // This is the responsibility of the CLI of the client to lock before running migration
 func (c *Client) Migrate(ctx context.Context, tables schema.Tables) error {
 	pgTables, err := c.listPgTables(ctx, tables)
@@ -258,11 +352,31 @@ func (c *Client) Migrate(ctx context.Context, tables schema.Tables) error {
 		return fmt.Errorf("failed listing postgres tables: %w", err)
 	}
 	tables = c.normalizeTables(tables)
+	repartitioned, err := c.repartitionedTables(ctx, tables, pgTables)
+	if err != nil {
+		return err
+	}
+	if c.migratePlanFile != "" {
+		plans := c.migrationPlan(tables, pgTables, repartitioned)
+		c.logMigrationPlan(plans)
+		return writeMigrationPlan(c.migratePlanFile, plans)
+	}
+	if err := c.createSchemaIfNotExist(ctx); err != nil {
+		return err
+	}
 	if c.spec.MigrateMode != specs.MigrateModeForced {
 		nonAutoMigrableTables, changes := c.nonAutoMigrableTables(tables, pgTables)
 		if len(nonAutoMigrableTables) > 0 {
 			return fmt.Errorf("tables %s with changes %v require force migration. use 'migrate_mode: forced'", strings.Join(nonAutoMigrableTables, ","), changes)
 		}
+		if len(repartitioned) > 0 {
+			names := make([]string, 0, len(repartitioned))
+			for name := range repartitioned {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("tables %s are partitioned differently than time_partitioning, and existing tables can't be repartitioned. use 'migrate_mode: forced' to recreate them", strings.Join(names, ","))
+		}
 	}
 
 	for _, table := range tables {
@@ -279,7 +393,7 @@ func (c *Client) Migrate(ctx context.Context, tables schema.Tables) error {
 			}
 		} else {
 			changes := table.GetChanges(pgTable)
-			if c.canAutoMigrate(changes) {
+			if c.canAutoMigrate(changes) && !repartitioned[table.Name] {
 				c.logger.Info().Str("table", table.Name).Msg("Table exists, auto-migrating")
 				if err := c.autoMigrateTable(ctx, table, changes); err != nil {
 					return err
@@ -306,9 +420,29 @@ func (c *Client) Migrate(ctx context.Context, tables schema.Tables) error {
 	return nil
 }
 
+// createSchemaIfNotExist creates the schema given by schema_name if it doesn't exist. It is looked up first, as
+// PostgreSQL requires the CREATE privilege on the database even if the schema exists.
+func (c *Client) createSchemaIfNotExist(ctx context.Context) error {
+	if c.pgSpec.SchemaName == "" {
+		return nil
+	}
+	var exists bool
+	if err := c.conn.QueryRow(ctx, "select exists(select 1 from pg_namespace where nspname = $1)", c.pgSpec.SchemaName).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up schema %s: %w", c.pgSpec.SchemaName, err)
+	}
+	if exists {
+		return nil
+	}
+	sql := "create schema if not exists " + pgx.Identifier{c.pgSpec.SchemaName}.Sanitize()
+	if _, err := c.conn.Exec(ctx, sql); err != nil {
+		return fmt.Errorf("failed to create schema %s: %w", c.pgSpec.SchemaName, err)
+	}
+	return nil
+}
+
 func (c *Client) dropTable(ctx context.Context, tableName string) error {
 	c.logger.Info().Str("table", tableName).Msg("Dropping table")
-	sql := "drop table " + tableName
+	sql := "drop table " + c.tableIdentifier(tableName).Sanitize()
 	if _, err := c.conn.Exec(ctx, sql); err != nil {
 		return fmt.Errorf("failed to drop table %s: %w", tableName, err)
 	}
@@ -319,7 +453,7 @@ func (c *Client) addColumn(ctx context.Context, tableName string, column schema.
 	c.logger.Info().Str("table", tableName).Str("column", column.Name).Msg("Column doesn't exist, creating")
 	columnName := pgx.Identifier{column.Name}.Sanitize()
 	columnType := c.SchemaTypeToPg(column.Type)
-	sql := "alter table " + tableName + " add column " + columnName + " " + columnType
+	sql := "alter table " + c.tableIdentifier(tableName).Sanitize() + " add column " + columnName + " " + columnType
 	if _, err := c.conn.Exec(ctx, sql); err != nil {
 		return fmt.Errorf("failed to add column %s on table %s: %w", column.Name, tableName, err)
 	}
@@ -328,7 +462,8 @@ func (c *Client) addColumn(ctx context.Context, tableName string, column schema.
 
 func (c *Client) createTableIfNotExist(ctx context.Context, table *schema.Table) error {
 	var sb strings.Builder
-	tableName := pgx.Identifier{table.Name}.Sanitize()
+	tableName := c.tableIdentifier(table.Name).Sanitize()
+	partitioned := c.partitioned(table)
 	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
 	sb.WriteString(tableName)
 	sb.WriteString(" (")
@@ -343,7 +478,8 @@ func (c *Client) createTableIfNotExist(ctx context.Context, table *schema.Table)
 		}
 		columnName := pgx.Identifier{col.Name}.Sanitize()
 		fieldDef := columnName + " " + pgType
-		if col.CreationOptions.Unique {
+		// unique constraints of a partitioned table have to include the partition column
+		if col.CreationOptions.Unique && !partitioned {
 			fieldDef += " UNIQUE"
 		}
 		if col.CreationOptions.NotNull {
@@ -360,13 +496,20 @@ func (c *Client) createTableIfNotExist(ctx context.Context, table *schema.Table)
 
 	if len(primaryKeys) > 0 {
 		// add composite PK constraint on primary key columns
+		// the constraint's index is named after it, and index names are unique per schema, so it has the table prefix
 		sb.WriteString(", CONSTRAINT ")
+		sb.WriteString(c.pgSpec.TablePrefix)
 		sb.WriteString(table.Name)
 		sb.WriteString("_cqpk PRIMARY KEY (")
 		sb.WriteString(strings.Join(primaryKeys, ","))
 		sb.WriteString(")")
 	}
 	sb.WriteString(")")
+	if partitioned {
+		sb.WriteString(" PARTITION BY RANGE (")
+		sb.WriteString(pgx.Identifier{schema.CqSyncTimeColumn.Name}.Sanitize())
+		sb.WriteString(")")
+	}
 	_, err := c.conn.Exec(ctx, sb.String())
 	if err != nil {
 		return fmt.Errorf("failed to create table %s: %w", table.Name, err)
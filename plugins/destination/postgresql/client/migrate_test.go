@@ -0,0 +1,94 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationPlan(t *testing.T) {
+	tables := schema.Tables{
+		{
+			Name: "new_table",
+			Columns: schema.ColumnList{
+				{Name: "id", Type: schema.TypeString},
+			},
+		},
+		{
+			Name: "add_column",
+			Columns: schema.ColumnList{
+				{Name: "id", Type: schema.TypeString},
+				{Name: "name", Type: schema.TypeString},
+			},
+		},
+		{
+			Name: "change_column",
+			Columns: schema.ColumnList{
+				{Name: "id", Type: schema.TypeInt},
+			},
+		},
+		{
+			Name: "unchanged",
+			Columns: schema.ColumnList{
+				{Name: "id", Type: schema.TypeString},
+			},
+		},
+		{
+			Name: "repartitioned",
+			Columns: schema.ColumnList{
+				{Name: "id", Type: schema.TypeString},
+			},
+		},
+	}
+	pgTables := schema.Tables{
+		{
+			Name: "add_column",
+			Columns: schema.ColumnList{
+				{Name: "id", Type: schema.TypeString},
+				{Name: "old", Type: schema.TypeString},
+			},
+		},
+		{
+			Name: "change_column",
+			Columns: schema.ColumnList{
+				{Name: "id", Type: schema.TypeString},
+			},
+		},
+		{
+			Name: "unchanged",
+			Columns: schema.ColumnList{
+				{Name: "id", Type: schema.TypeString},
+			},
+		},
+		{
+			Name: "repartitioned",
+			Columns: schema.ColumnList{
+				{Name: "id", Type: schema.TypeString},
+			},
+		},
+	}
+
+	c := &Client{}
+	plans := c.migrationPlan(tables, pgTables, map[string]bool{"repartitioned": true})
+	require.Equal(t, []tableMigrationPlan{
+		{Table: "new_table", Action: migrationActionCreate},
+		{Table: "add_column", Action: migrationActionAlter, AddColumns: []string{"name"}, RemoveColumns: []string{"old"}},
+		{Table: "change_column", Action: migrationActionRecreate, ChangeColumns: []string{"id"}, RequiresForced: true},
+		{Table: "unchanged", Action: migrationActionNone},
+		{Table: "repartitioned", Action: migrationActionRecreate, RequiresForced: true},
+	}, plans)
+}
+
+func TestWriteMigrationPlan(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	plans := []tableMigrationPlan{
+		{Table: "change_column", Action: migrationActionRecreate, ChangeColumns: []string{"id"}, RequiresForced: true},
+	}
+	require.NoError(t, writeMigrationPlan(path, plans))
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"table":"change_column","action":"recreate","change_columns":["id"],"requires_forced_migration":true}]`, string(b))
+}
@@ -0,0 +1,213 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// maxIdentifierLength is the length postgres truncates identifiers to
+	maxIdentifierLength  = 63
+	partitionSuffix      = "_p"
+	partitionBoundLayout = "2006-01-02 15:04:05"
+
+	selectPartitions = `
+SELECT
+	child.relname
+FROM
+	pg_catalog.pg_inherits
+	INNER JOIN
+	pg_catalog.pg_class parent ON parent.oid = pg_inherits.inhparent
+	INNER JOIN
+	pg_catalog.pg_class child ON child.oid = pg_inherits.inhrelid
+	INNER JOIN
+	pg_catalog.pg_namespace ON pg_namespace.oid = parent.relnamespace
+WHERE
+	pg_namespace.nspname = $1
+	AND parent.relname = $2
+`
+
+	selectPartitionedTables = `
+SELECT
+	pg_class.relname
+FROM
+	pg_catalog.pg_partitioned_table
+	INNER JOIN
+	pg_catalog.pg_class ON pg_class.oid = pg_partitioned_table.partrelid
+	INNER JOIN
+	pg_catalog.pg_namespace ON pg_namespace.oid = pg_class.relnamespace
+WHERE
+	pg_namespace.nspname = $1
+`
+)
+
+// partitioned returns true if the table is created partitioned by range of _cq_sync_time
+func (c *Client) partitioned(table *schema.Table) bool {
+	return c.pgSpec.TimePartitioning != TimePartitioningOptionNone && table.Columns.Get(schema.CqSyncTimeColumn.Name) != nil
+}
+
+func (c *Client) partitionLayout() string {
+	if c.pgSpec.TimePartitioning == TimePartitioningOptionMonth {
+		return "200601"
+	}
+	return "20060102"
+}
+
+// partitionRange returns the range of the partition the sync time belongs to
+func (c *Client) partitionRange(syncTime time.Time) (time.Time, time.Time) {
+	syncTime = syncTime.UTC()
+	if c.pgSpec.TimePartitioning == TimePartitioningOptionMonth {
+		start := time.Date(syncTime.Year(), syncTime.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(syncTime.Year(), syncTime.Month(), syncTime.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// partitionName returns the name of the partition of the table that starts at start. Postgres truncates long
+// identifiers, so long table names are shortened and made unique with a hash of the full name instead.
+func (c *Client) partitionName(tableName string, start time.Time) string {
+	base := c.pgSpec.TablePrefix + tableName
+	suffix := partitionSuffix + start.Format(c.partitionLayout())
+	if len(base)+len(suffix) <= maxIdentifierLength {
+		return base + suffix
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(base))
+	hash := fmt.Sprintf("_%08x", h.Sum32())
+	return base[:maxIdentifierLength-len(suffix)-len(hash)] + hash + suffix
+}
+
+// partitionStart parses the start of the partition from its name
+func (c *Client) partitionStart(partitionName string) (time.Time, bool) {
+	i := strings.LastIndex(partitionName, partitionSuffix)
+	if i < 0 {
+		return time.Time{}, false
+	}
+	start, err := time.ParseInLocation(c.partitionLayout(), partitionName[i+len(partitionSuffix):], time.UTC)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return start, true
+}
+
+// partitionCache is the sync time of the last resource of every table that a partition was ensured for by a Write
+// call. The resources of a sync share the sync time, so the partition of a table is only looked up when it changes.
+type partitionCache map[string]time.Time
+
+// ensurePartition creates the partition for the sync time of the resource if it is missing, and then drops the
+// partitions that are older than the retention.
+func (c *Client) ensurePartition(ctx context.Context, cache partitionCache, table *schema.Table, data []any) error {
+	if !c.partitioned(table) {
+		return nil
+	}
+	syncTime, ok := data[table.Columns.Index(schema.CqSyncTimeColumn.Name)].(*pgtype.Timestamptz)
+	if !ok || !syncTime.Valid {
+		return fmt.Errorf("resource of partitioned table %s has no %s", table.Name, schema.CqSyncTimeColumn.Name)
+	}
+	if last, ok := cache[table.Name]; ok && last.Equal(syncTime.Time) {
+		return nil
+	}
+	if err := c.createPartition(ctx, table, syncTime.Time); err != nil {
+		return err
+	}
+	cache[table.Name] = syncTime.Time
+	return nil
+}
+
+// createPartition creates the partition of the table the sync time belongs to, unless this client created it or
+// found it to exist before
+func (c *Client) createPartition(ctx context.Context, table *schema.Table, syncTime time.Time) error {
+	start, end := c.partitionRange(syncTime)
+	name := c.partitionName(table.Name, start)
+
+	c.partitionsLock.RLock()
+	exists := c.partitions[name]
+	c.partitionsLock.RUnlock()
+	if exists {
+		return nil
+	}
+
+	c.partitionsLock.Lock()
+	defer c.partitionsLock.Unlock()
+	if c.partitions[name] {
+		return nil
+	}
+	sql := fmt.Sprintf("create table if not exists %s partition of %s for values from ('%s') to ('%s')",
+		pgx.Identifier{c.currentSchemaName, name}.Sanitize(),
+		c.tableIdentifier(table.Name).Sanitize(),
+		start.Format(partitionBoundLayout), end.Format(partitionBoundLayout))
+	if _, err := c.conn.Exec(ctx, sql); err != nil {
+		return batchErr(fmt.Sprintf("failed to create partition %s", name), err)
+	}
+	c.partitions[name] = true
+	return c.dropExpiredPartitions(ctx, table)
+}
+
+// repartitionedTables returns the existing tables that are partitioned while time_partitioning is disabled, or the
+// other way around. Such tables have to be recreated, as postgres can't partition an existing table.
+func (c *Client) repartitionedTables(ctx context.Context, tables schema.Tables, pgTables schema.Tables) (map[string]bool, error) {
+	partitioned := make(map[string]bool)
+	if c.pgType != pgTypeCockroachDB {
+		rows, err := c.conn.Query(ctx, selectPartitionedTables, c.currentSchemaName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list partitioned tables: %w", err)
+		}
+		names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return nil, fmt.Errorf("failed to list partitioned tables: %w", err)
+		}
+		for _, name := range names {
+			partitioned[name] = true
+		}
+	}
+	repartitioned := make(map[string]bool)
+	for _, table := range tables {
+		if pgTables.Get(table.Name) == nil {
+			continue
+		}
+		if c.partitioned(table) != partitioned[c.pgSpec.TablePrefix+table.Name] {
+			repartitioned[table.Name] = true
+		}
+	}
+	return repartitioned, nil
+}
+
+// dropExpiredPartitions drops the partitions of the table whose range ended before the retention
+func (c *Client) dropExpiredPartitions(ctx context.Context, table *schema.Table) error {
+	retention, err := c.pgSpec.RetentionDuration()
+	if err != nil || retention == 0 {
+		return err
+	}
+	rows, err := c.conn.Query(ctx, selectPartitions, c.currentSchemaName, c.pgSpec.TablePrefix+table.Name)
+	if err != nil {
+		return fmt.Errorf("failed to list partitions of %s: %w", table.Name, err)
+	}
+	partitions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to list partitions of %s: %w", table.Name, err)
+	}
+	cutoff := time.Now().UTC().Add(-retention)
+	for _, partition := range partitions {
+		start, ok := c.partitionStart(partition)
+		if !ok {
+			continue
+		}
+		if _, end := c.partitionRange(start); end.After(cutoff) {
+			continue
+		}
+		c.logger.Info().Str("table", table.Name).Str("partition", partition).Msg("Dropping partition older than retention")
+		if _, err := c.conn.Exec(ctx, "drop table "+pgx.Identifier{c.currentSchemaName, partition}.Sanitize()); err != nil {
+			return batchErr(fmt.Sprintf("failed to drop partition %s", partition), err)
+		}
+		delete(c.partitions, partition)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionRange(t *testing.T) {
+	syncTime := time.Date(2023, 1, 31, 15, 4, 5, 0, time.UTC)
+
+	c := &Client{pgSpec: Spec{TimePartitioning: TimePartitioningOptionDay}}
+	start, end := c.partitionRange(syncTime)
+	require.Equal(t, time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), end)
+	require.Equal(t, "test_table_p20230131", c.partitionName("test_table", start))
+
+	c = &Client{pgSpec: Spec{TimePartitioning: TimePartitioningOptionMonth, TablePrefix: "cq_"}}
+	start, end = c.partitionRange(syncTime)
+	require.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), end)
+	name := c.partitionName("test_table", start)
+	require.Equal(t, "cq_test_table_p202301", name)
+	parsed, ok := c.partitionStart(name)
+	require.True(t, ok)
+	require.Equal(t, start, parsed)
+}
+
+func TestPartitionNameLongTable(t *testing.T) {
+	c := &Client{pgSpec: Spec{TimePartitioning: TimePartitioningOptionDay}}
+	tableName := strings.Repeat("a", 60)
+	first := c.partitionName(tableName, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	second := c.partitionName(tableName, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC))
+	require.LessOrEqual(t, len(first), maxIdentifierLength)
+	require.NotEqual(t, first, second)
+	require.NotEqual(t, c.partitionName(tableName+"b", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)), first)
+
+	parsed, ok := c.partitionStart(second)
+	require.True(t, ok)
+	require.Equal(t, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), parsed)
+}
+
+func TestSpecRetention(t *testing.T) {
+	spec := Spec{TimePartitioning: TimePartitioningOptionDay, Retention: "90d"}
+	spec.SetDefaults()
+	require.NoError(t, spec.Validate())
+	d, err := spec.RetentionDuration()
+	require.NoError(t, err)
+	require.Equal(t, 90*24*time.Hour, d)
+
+	spec.Retention = "36h"
+	d, err = spec.RetentionDuration()
+	require.NoError(t, err)
+	require.Equal(t, 36*time.Hour, d)
+
+	spec.Retention = "-1d"
+	require.ErrorContains(t, spec.Validate(), "must be positive")
+
+	spec = Spec{Retention: "90d"}
+	spec.SetDefaults()
+	require.ErrorContains(t, spec.Validate(), "retention requires time_partitioning")
+
+	spec = Spec{WriteMethod: WriteMethodBatch, TimePartitioning: "hour"}
+	require.ErrorContains(t, spec.Validate(), "hour is not a valid option")
+}
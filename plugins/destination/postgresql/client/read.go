@@ -19,7 +19,7 @@ func (c *Client) Read(ctx context.Context, table *schema.Table, sourceName strin
 		colNames = append(colNames, pgx.Identifier{col.Name}.Sanitize())
 	}
 	cols := strings.Join(colNames, ",")
-	sql := fmt.Sprintf(readSQL, cols, pgx.Identifier{table.Name}.Sanitize())
+	sql := fmt.Sprintf(readSQL, cols, c.tableIdentifier(table.Name).Sanitize())
 	rows, err := c.conn.Query(ctx, sql, sourceName)
 	if err != nil {
 		return err
@@ -1,9 +1,107 @@
 package client
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type WriteMethod string
+
+const (
+	// WriteMethodBatch queues an insert or upsert statement per resource in a pgx.Batch
+	WriteMethodBatch WriteMethod = "batch"
+	// WriteMethodCopyFrom bulk loads the resources with COPY FROM
+	WriteMethodCopyFrom WriteMethod = "copy_from"
+)
+
+type TimePartitioningOption string
+
+const (
+	TimePartitioningOptionNone  = "none"
+	TimePartitioningOptionDay   = "day"
+	TimePartitioningOptionMonth = "month"
+)
+
+var TimePartitioningOptions = []TimePartitioningOption{
+	TimePartitioningOptionNone,
+	TimePartitioningOptionDay,
+	TimePartitioningOptionMonth,
+}
+
+func (t TimePartitioningOption) Validate() error {
+	for _, v := range TimePartitioningOptions {
+		if t == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v is not a valid option for time partitioning. Options are: %v", string(t), TimePartitioningOptions)
+}
+
 type Spec struct {
-	ConnectionString string   `json:"connection_string,omitempty"`
-	PgxLogLevel      LogLevel `json:"pgx_log_level,omitempty"`
+	ConnectionString string      `json:"connection_string,omitempty"`
+	PgxLogLevel      LogLevel    `json:"pgx_log_level,omitempty"`
+	WriteMethod      WriteMethod `json:"write_method,omitempty"`
+	// SchemaName is the schema the tables are created in. Defaults to the current schema of the connection.
+	SchemaName string `json:"schema_name,omitempty"`
+	// TablePrefix is prepended to the names of all tables
+	TablePrefix string `json:"table_prefix,omitempty"`
+	// TimePartitioning partitions the tables created by migrate by range of _cq_sync_time
+	TimePartitioning TimePartitioningOption `json:"time_partitioning,omitempty"`
+	// Retention is how long partitions are kept, for example 90d or 720h. Partitions are kept forever if it's empty.
+	Retention string `json:"retention,omitempty"`
+}
+
+func (s *Spec) SetDefaults() {
+	if s.WriteMethod == "" {
+		s.WriteMethod = WriteMethodBatch
+	}
+	if s.TimePartitioning == "" {
+		s.TimePartitioning = TimePartitioningOptionNone
+	}
+}
+
+func (s *Spec) Validate() error {
+	switch s.WriteMethod {
+	case WriteMethodBatch, WriteMethodCopyFrom:
+	default:
+		return fmt.Errorf("invalid write_method %q. Options are: %s, %s", s.WriteMethod, WriteMethodBatch, WriteMethodCopyFrom)
+	}
+	if err := s.TimePartitioning.Validate(); err != nil {
+		return fmt.Errorf("time_partitioning: %w", err)
+	}
+	if s.Retention != "" {
+		if s.TimePartitioning == TimePartitioningOptionNone {
+			return fmt.Errorf("retention requires time_partitioning")
+		}
+		if _, err := s.RetentionDuration(); err != nil {
+			return fmt.Errorf("retention: %w", err)
+		}
+	}
+	return nil
 }
 
-func (*Spec) SetDefaults() {
+// RetentionDuration parses Retention, which is a Go duration or a number of days such as 90d
+func (s *Spec) RetentionDuration() (time.Duration, error) {
+	if s.Retention == "" {
+		return 0, nil
+	}
+	var d time.Duration
+	if strings.HasSuffix(s.Retention, "d") {
+		n, err := strconv.Atoi(strings.TrimSuffix(s.Retention, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days %q", s.Retention)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s.Retention); err != nil {
+			return 0, err
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive, got %s", s.Retention)
+	}
+	return d, nil
 }
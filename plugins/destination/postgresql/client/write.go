@@ -54,6 +54,16 @@ func pgErrToStr(err *pgconn.PgError) string {
 	return sb.String()
 }
 
+// batchErr adds the details of a postgres error to the error of a failed write
+func batchErr(msg string, err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		// not recoverable error
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	return fmt.Errorf("%s with pgerror: %s: %w", msg, pgErrToStr(pgErr), err)
+}
+
 func (c *Client) populateConstraintNames(ctx context.Context, tables schema.Tables) error {
 	pgTables, err := c.listPgTables(ctx, tables)
 	if err != nil {
@@ -70,16 +80,23 @@ func (c *Client) populateConstraintNames(ctx context.Context, tables schema.Tabl
 }
 
 func (c *Client) Write(ctx context.Context, tables schema.Tables, res <-chan *destination.ClientResource) error {
-	var sql string
-	batch := &pgx.Batch{}
 	if err := c.populateConstraintNames(ctx, tables); err != nil {
 		return err
 	}
+	if c.pgSpec.WriteMethod == WriteMethodCopyFrom {
+		return c.copyFrom(ctx, tables, res)
+	}
+	var sql string
+	batch := &pgx.Batch{}
+	partitions := make(partitionCache)
 	for r := range res {
 		table := tables.Get(r.TableName)
 		if table == nil {
 			panic(fmt.Errorf("table %s not found", r.TableName))
 		}
+		if err := c.ensurePartition(ctx, partitions, table, r.Data); err != nil {
+			return err
+		}
 		if c.spec.WriteMode == specs.WriteModeAppend {
 			sql = c.insert(table)
 		} else {
@@ -94,12 +111,7 @@ func (c *Client) Write(ctx context.Context, tables schema.Tables, res <-chan *de
 		if batchSize >= c.batchSize {
 			br := c.conn.SendBatch(ctx, batch)
 			if err := br.Close(); err != nil {
-				var pgErr *pgconn.PgError
-				if !errors.As(err, &pgErr) {
-					// not recoverable error
-					return fmt.Errorf("failed to execute batch: %w", err)
-				}
-				return fmt.Errorf("failed to execute batch with pgerror: %s: %w", pgErrToStr(pgErr), err)
+				return batchErr("failed to execute batch", err)
 			}
 			atomic.AddUint64(&c.metrics.Writes, uint64(batchSize))
 			batch = &pgx.Batch{}
@@ -110,12 +122,7 @@ func (c *Client) Write(ctx context.Context, tables schema.Tables, res <-chan *de
 	if batchSize > 0 {
 		br := c.conn.SendBatch(ctx, batch)
 		if err := br.Close(); err != nil {
-			var pgErr *pgconn.PgError
-			if !errors.As(err, &pgErr) {
-				// not recoverable error
-				return fmt.Errorf("failed to execute batch: %w", err)
-			}
-			return fmt.Errorf("failed to execute batch with pgerror: %s: %w", pgErrToStr(pgErr), err)
+			return batchErr("failed to execute batch", err)
 		}
 		atomic.AddUint64(&c.metrics.Writes, uint64(batchSize))
 	}
@@ -123,10 +130,10 @@ func (c *Client) Write(ctx context.Context, tables schema.Tables, res <-chan *de
 	return nil
 }
 
-func (*Client) insert(table *schema.Table) string {
+func (c *Client) insert(table *schema.Table) string {
 	var sb strings.Builder
 	sb.WriteString("insert into ")
-	sb.WriteString(pgx.Identifier{table.Name}.Sanitize())
+	sb.WriteString(c.tableIdentifier(table.Name).Sanitize())
 	sb.WriteString(" (")
 	columns := table.Columns
 	columnsLen := len(columns)
@@ -150,9 +157,13 @@ func (*Client) insert(table *schema.Table) string {
 }
 
 func (c *Client) upsert(table *schema.Table) string {
+	return c.insert(table) + onConflictUpdate(table)
+}
+
+// onConflictUpdate returns the clause that updates all columns of a row that conflicts with the primary key
+func onConflictUpdate(table *schema.Table) string {
 	var sb strings.Builder
 
-	sb.WriteString(c.insert(table))
 	columns := table.Columns
 	columnsLen := len(columns)
 
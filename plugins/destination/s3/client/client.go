@@ -9,8 +9,10 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/glue"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 
+	"github.com/cloudquery/cloudquery/plugins/destination/internal/manifest"
 	"github.com/cloudquery/filetypes"
 	"github.com/cloudquery/plugin-sdk/plugins/destination"
 	"github.com/cloudquery/plugin-sdk/specs"
@@ -26,6 +28,9 @@ type Client struct {
 	s3Client   *s3.Client
 	uploader   *manager.Uploader
 	downloader *manager.Downloader
+	glueClient *glue.Client
+	// manifests is nil unless manifests or _SUCCESS markers are written
+	manifests *manifest.Manifests
 	*filetypes.Client
 }
 
@@ -51,6 +56,9 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 		return nil, fmt.Errorf("failed to create filetypes client: %w", err)
 	}
 	c.Client = filetypesClient
+	if c.pluginSpec.Manifest || c.pluginSpec.SuccessMarker {
+		c.manifests = manifest.New(c.pluginSpec.Path, string(c.pluginSpec.Format))
+	}
 
 	cfg, err := config.LoadDefaultConfig(ctx, config.WithDefaultRegion("us-east-1"))
 	if err != nil {
@@ -61,12 +69,15 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 	c.s3Client = s3.NewFromConfig(cfg)
 	c.uploader = manager.NewUploader(c.s3Client)
 	c.downloader = manager.NewDownloader(c.s3Client)
+	if c.pluginSpec.GlueDatabase != "" {
+		c.glueClient = glue.NewFromConfig(cfg)
+	}
 
 	// we want to run this test because we want it to fail early if the bucket is not accessible
 	timeNow := time.Now().UTC()
 	if _, err := c.uploader.Upload(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(c.pluginSpec.Bucket),
-		Key:    aws.String(replacePathVariables(c.pluginSpec.Path, "TEST_TABLE", "TEST_UUID", timeNow)),
+		Key:    aws.String(replacePathVariables(c.pluginSpec.Path, "TEST_TABLE", "TEST_SOURCE", "TEST_SYNC_ID", "TEST_UUID", timeNow)),
 		Body:   bytes.NewReader([]byte("")),
 	}); err != nil {
 		return nil, fmt.Errorf("failed to write test file to S3: %w", err)
@@ -74,6 +85,23 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 	return c, nil
 }
 
-func (*Client) Close(ctx context.Context) error {
+// Close writes the manifests and _SUCCESS markers of the objects written during the sync
+func (c *Client) Close(ctx context.Context) error {
+	if c.manifests == nil {
+		return nil
+	}
+	files, err := c.manifests.Files(c.pluginSpec.Manifest, c.pluginSpec.SuccessMarker)
+	if err != nil {
+		return fmt.Errorf("failed to create manifests: %w", err)
+	}
+	for _, f := range files {
+		if _, err := c.uploader.Upload(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(c.pluginSpec.Bucket),
+			Key:    aws.String(f.Key),
+			Body:   bytes.NewReader(f.Body),
+		}); err != nil {
+			return fmt.Errorf("failed to write %s to S3: %w", f.Key, err)
+		}
+	}
 	return nil
 }
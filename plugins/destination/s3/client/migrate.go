@@ -2,11 +2,88 @@ package client
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/glue"
+	"github.com/aws/aws-sdk-go-v2/service/glue/types"
 	"github.com/cloudquery/plugin-sdk/schema"
 )
 
-func (*Client) Migrate(ctx context.Context, tables schema.Tables) error {
-	// migrate is not needed in append mode
+// Migrate creates or updates the table definitions of the tables in the Glue Data Catalog and writes their DDL to
+// the local file, if enabled. Otherwise, migrate is not needed in append mode.
+func (c *Client) Migrate(ctx context.Context, tables schema.Tables) error {
+	if c.pluginSpec.GlueDatabase == "" && c.pluginSpec.DDLFile == "" {
+		return nil
+	}
+	var ddl strings.Builder
+	for _, table := range tables.FlattenTables() {
+		def, err := newTableDefinition(&c.pluginSpec, table)
+		if err != nil {
+			return fmt.Errorf("failed to create table definition of %s: %w", table.Name, err)
+		}
+		ddl.WriteString(def.ddl(c.pluginSpec.GlueDatabase))
+		ddl.WriteString("\n")
+		if c.pluginSpec.GlueDatabase != "" {
+			if err := c.applyTableDefinition(ctx, def); err != nil {
+				return fmt.Errorf("failed to apply table definition of %s: %w", table.Name, err)
+			}
+		}
+	}
+	if c.pluginSpec.DDLFile != "" {
+		if err := os.WriteFile(c.pluginSpec.DDLFile, []byte(ddl.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write DDL file: %w", err)
+		}
+	}
 	return nil
 }
+
+// applyTableDefinition creates the table in the Glue database, or updates it if it exists
+func (c *Client) applyTableDefinition(ctx context.Context, def *tableDefinition) error {
+	input := &types.TableInput{
+		Name:          aws.String(def.name),
+		TableType:     aws.String("EXTERNAL_TABLE"),
+		Parameters:    map[string]string{"EXTERNAL": "TRUE"},
+		PartitionKeys: glueColumns(def.partitionKeys),
+		StorageDescriptor: &types.StorageDescriptor{
+			Columns:      glueColumns(def.columns),
+			Location:     aws.String(def.location),
+			InputFormat:  aws.String(def.inputFormat),
+			OutputFormat: aws.String(def.outputFormat),
+			SerdeInfo: &types.SerDeInfo{
+				SerializationLibrary: aws.String(def.serde),
+				Parameters:           def.serdeParameters,
+			},
+		},
+	}
+	for k, v := range def.parameters {
+		input.Parameters[k] = v
+	}
+
+	c.logger.Debug().Str("table", def.name).Str("database", c.pluginSpec.GlueDatabase).Msg("Creating table definition")
+	_, err := c.glueClient.CreateTable(ctx, &glue.CreateTableInput{
+		DatabaseName: aws.String(c.pluginSpec.GlueDatabase),
+		TableInput:   input,
+	})
+	var exists *types.AlreadyExistsException
+	if !errors.As(err, &exists) {
+		return err
+	}
+	c.logger.Debug().Str("table", def.name).Str("database", c.pluginSpec.GlueDatabase).Msg("Updating table definition")
+	_, err = c.glueClient.UpdateTable(ctx, &glue.UpdateTableInput{
+		DatabaseName: aws.String(c.pluginSpec.GlueDatabase),
+		TableInput:   input,
+	})
+	return err
+}
+
+func glueColumns(columns []tableColumn) []types.Column {
+	glueColumns := make([]types.Column, len(columns))
+	for i, column := range columns {
+		glueColumns[i] = types.Column{Name: aws.String(column.name), Type: aws.String(column.typ)}
+	}
+	return glueColumns
+}
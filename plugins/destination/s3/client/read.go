@@ -19,6 +19,7 @@ func (c *Client) Read(ctx context.Context, table *schema.Table, sourceName strin
 		return fmt.Errorf("reading is not supported when no_rotate is false. Table: %q; Source: %q", table.Name, sourceName)
 	}
 	name := strings.ReplaceAll(c.pluginSpec.Path, PathVarTable, table.Name)
+	name = strings.ReplaceAll(name, PathVarSource, sourceName)
 	writerAtBuffer := manager.NewWriteAtBuffer(make([]byte, 0, maxFileSize))
 	_, err := c.downloader.Download(ctx,
 		writerAtBuffer,
@@ -15,6 +15,12 @@ type Spec struct {
 	Region   string `json:"region,omitempty"`
 	Path     string `json:"path,omitempty"`
 	Athena   bool   `json:"athena,omitempty"`
+
+	Manifest      bool `json:"manifest,omitempty"`
+	SuccessMarker bool `json:"success_marker,omitempty"`
+
+	GlueDatabase string `json:"glue_database,omitempty"`
+	DDLFile      string `json:"ddl_file,omitempty"`
 }
 
 func (s *Spec) SetDefaults() {
@@ -53,5 +59,11 @@ func (s *Spec) Validate() error {
 		return fmt.Errorf("format is required")
 	}
 
+	if s.GlueDatabase != "" || s.DDLFile != "" {
+		if err := validateTableDefinitionPath(s.Path); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
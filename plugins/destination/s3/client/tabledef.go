@@ -0,0 +1,283 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cloudquery/filetypes"
+	"github.com/cloudquery/filetypes/csv"
+	"github.com/cloudquery/plugin-sdk/schema"
+)
+
+const (
+	hiveTextInputFormat  = "org.apache.hadoop.mapred.TextInputFormat"
+	hiveTextOutputFormat = "org.apache.hadoop.hive.ql.io.HiveIgnoreKeyTextOutputFormat"
+
+	// projectionStartYear is the first year of the partition projection of {{YEAR}}
+	projectionStartYear = 2020
+	projectionEndYear   = 2100
+)
+
+var rePathVariable = regexp.MustCompile(`{{[A-Z_]+}}`)
+
+// partitionVariable is a path variable that becomes a partition key of the table definitions. The partitions are
+// projected by Athena, so they don't need to be added to the Glue Data Catalog.
+type partitionVariable struct {
+	column     string
+	typ        string
+	projection map[string]string
+}
+
+var partitionVariables = map[string]partitionVariable{
+	YearVar: {column: "cq_year", typ: "int", projection: map[string]string{
+		"type": "integer", "range": fmt.Sprintf("%d,%d", projectionStartYear, projectionEndYear),
+	}},
+	MonthVar:      {column: "cq_month", typ: "int", projection: map[string]string{"type": "integer", "range": "1,12", "digits": "2"}},
+	DayVar:        {column: "cq_day", typ: "int", projection: map[string]string{"type": "integer", "range": "1,31", "digits": "2"}},
+	HourVar:       {column: "cq_hour", typ: "int", projection: map[string]string{"type": "integer", "range": "0,23", "digits": "2"}},
+	MinuteVar:     {column: "cq_minute", typ: "int", projection: map[string]string{"type": "integer", "range": "0,59", "digits": "2"}},
+	PathVarSource: {column: "cq_source", typ: "string", projection: map[string]string{"type": "injected"}},
+	PathVarSyncID: {column: "cq_sync_id", typ: "string", projection: map[string]string{"type": "injected"}},
+}
+
+type tableColumn struct {
+	name string
+	typ  string
+}
+
+// tableDefinition is the Glue Data Catalog definition of the table the objects of a CloudQuery table are queried
+// with in Athena
+type tableDefinition struct {
+	name            string
+	columns         []tableColumn
+	partitionKeys   []tableColumn
+	location        string
+	inputFormat     string
+	outputFormat    string
+	serde           string
+	serdeParameters map[string]string
+	parameters      map[string]string
+}
+
+// validateTableDefinitionPath checks that the objects of every table can be told apart by their directory
+func validateTableDefinitionPath(specPath string) error {
+	dir := path.Dir(specPath)
+	if !strings.Contains(dir, PathVarTable) {
+		return fmt.Errorf("path should contain %s in a directory to create table definitions, e.g. %q", PathVarTable, "path/"+PathVarTable+"/"+PathVarUUID+".parquet")
+	}
+	for _, v := range rePathVariable.FindAllString(dir, -1) {
+		if _, ok := partitionVariables[v]; !ok && v != PathVarTable {
+			return fmt.Errorf("path should not contain %s in a directory to create table definitions", v)
+		}
+	}
+	return nil
+}
+
+func newTableDefinition(spec *Spec, table *schema.Table) (*tableDefinition, error) {
+	def := &tableDefinition{
+		name:       table.Name,
+		columns:    make([]tableColumn, len(table.Columns)),
+		parameters: map[string]string{"classification": string(spec.Format)},
+	}
+	for i, column := range table.Columns {
+		def.columns[i] = tableColumn{name: column.Name, typ: hiveType(column.Type, spec.Format)}
+	}
+
+	switch spec.Format {
+	case filetypes.FormatTypeParquet:
+		def.inputFormat = "org.apache.hadoop.hive.ql.io.parquet.MapredParquetInputFormat"
+		def.outputFormat = "org.apache.hadoop.hive.ql.io.parquet.MapredParquetOutputFormat"
+		def.serde = "org.apache.hadoop.hive.ql.io.parquet.serde.ParquetHiveSerDe"
+	case filetypes.FormatTypeJSON:
+		def.inputFormat = hiveTextInputFormat
+		def.outputFormat = hiveTextOutputFormat
+		def.serde = "org.openx.data.jsonserde.JsonSerDe"
+	case filetypes.FormatTypeCSV:
+		csvSpec, err := parseCSVSpec(spec.FileSpec)
+		if err != nil {
+			return nil, err
+		}
+		def.inputFormat = hiveTextInputFormat
+		def.outputFormat = hiveTextOutputFormat
+		def.serde = "org.apache.hadoop.hive.serde2.OpenCSVSerde"
+		def.serdeParameters = map[string]string{"separatorChar": csvSpec.Delimiter, "quoteChar": `"`}
+		if !csvSpec.SkipHeader {
+			def.parameters["skip.header.line.count"] = "1"
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %s", spec.Format)
+	}
+
+	// the location is the directory of the objects up to the first path variable, and the rest of the directory
+	// is projected as partitions
+	dir := path.Dir(strings.ReplaceAll(spec.Path, PathVarTable, table.Name))
+	segments := strings.Split(dir, "/")
+	static := len(segments)
+	for i, segment := range segments {
+		if rePathVariable.MatchString(segment) {
+			static = i
+			break
+		}
+	}
+	def.location = s3Location(spec.Bucket, strings.Join(segments[:static], "/"))
+	if static == len(segments) {
+		return def, nil
+	}
+
+	template := dir
+	for _, v := range rePathVariable.FindAllString(dir, -1) {
+		pv, ok := partitionVariables[v]
+		if !ok {
+			return nil, fmt.Errorf("path variable %s can't be used as a partition", v)
+		}
+		if !strings.Contains(template, v) {
+			// the variable is used more than once
+			continue
+		}
+		template = strings.ReplaceAll(template, v, "${"+pv.column+"}")
+		def.partitionKeys = append(def.partitionKeys, tableColumn{name: pv.column, typ: pv.typ})
+		for k, value := range pv.projection {
+			def.parameters["projection."+pv.column+"."+k] = value
+		}
+	}
+	def.parameters["projection.enabled"] = "true"
+	def.parameters["storage.location.template"] = s3Location(spec.Bucket, template)
+	return def, nil
+}
+
+func s3Location(bucket string, prefix string) string {
+	if prefix == "" || prefix == "." {
+		return "s3://" + bucket + "/"
+	}
+	return "s3://" + bucket + "/" + prefix + "/"
+}
+
+// hiveType returns the type of the column in the objects of the format. CSV values are all read as strings.
+func hiveType(t schema.ValueType, format filetypes.FormatType) string {
+	if format == filetypes.FormatTypeCSV {
+		return "string"
+	}
+	switch t {
+	case schema.TypeBool:
+		return "boolean"
+	case schema.TypeInt:
+		return "bigint"
+	case schema.TypeFloat:
+		return "double"
+	case schema.TypeTimestamp:
+		return "timestamp"
+	case schema.TypeByteArray:
+		if format == filetypes.FormatTypeParquet {
+			return "binary"
+		}
+		// JSON encodes byte arrays as base64 strings
+		return "string"
+	case schema.TypeIntArray:
+		return "array<bigint>"
+	case schema.TypeStringArray, schema.TypeUUIDArray, schema.TypeCIDRArray, schema.TypeInetArray, schema.TypeMacAddrArray:
+		return "array<string>"
+	default:
+		// strings, UUIDs, network addresses and JSON, which is kept as its JSON text
+		return "string"
+	}
+}
+
+func parseCSVSpec(spec *filetypes.FileSpec) (*csv.Spec, error) {
+	csvSpec := &csv.Spec{}
+	if spec.FormatSpec != nil {
+		b, err := json.Marshal(spec.FormatSpec)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(csvSpec); err != nil {
+			return nil, fmt.Errorf("failed to parse csv format_spec: %w", err)
+		}
+	}
+	csvSpec.SetDefaults()
+	return csvSpec, nil
+}
+
+// ddl returns the Athena statement creating the table in the database, or in the current database if database is
+// empty
+func (def *tableDefinition) ddl(database string) string {
+	var sb strings.Builder
+	sb.WriteString("CREATE EXTERNAL TABLE IF NOT EXISTS ")
+	if database != "" {
+		sb.WriteString(quoteIdentifier(database))
+		sb.WriteString(".")
+	}
+	sb.WriteString(quoteIdentifier(def.name))
+	sb.WriteString(" (\n")
+	writeColumns(&sb, def.columns)
+	sb.WriteString(")\n")
+	if len(def.partitionKeys) > 0 {
+		sb.WriteString("PARTITIONED BY (\n")
+		writeColumns(&sb, def.partitionKeys)
+		sb.WriteString(")\n")
+	}
+	sb.WriteString("ROW FORMAT SERDE ")
+	sb.WriteString(quoteString(def.serde))
+	sb.WriteString("\n")
+	if len(def.serdeParameters) > 0 {
+		sb.WriteString("WITH SERDEPROPERTIES (\n")
+		writeProperties(&sb, def.serdeParameters)
+		sb.WriteString(")\n")
+	}
+	sb.WriteString("STORED AS INPUTFORMAT ")
+	sb.WriteString(quoteString(def.inputFormat))
+	sb.WriteString("\nOUTPUTFORMAT ")
+	sb.WriteString(quoteString(def.outputFormat))
+	sb.WriteString("\nLOCATION ")
+	sb.WriteString(quoteString(def.location))
+	sb.WriteString("\nTBLPROPERTIES (\n")
+	writeProperties(&sb, def.parameters)
+	sb.WriteString(");\n")
+	return sb.String()
+}
+
+func writeColumns(sb *strings.Builder, columns []tableColumn) {
+	for i, column := range columns {
+		sb.WriteString("  ")
+		sb.WriteString(quoteIdentifier(column.name))
+		sb.WriteString(" ")
+		sb.WriteString(column.typ)
+		if i < len(columns)-1 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n")
+	}
+}
+
+func writeProperties(sb *strings.Builder, properties map[string]string) {
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		sb.WriteString("  ")
+		sb.WriteString(quoteString(k))
+		sb.WriteString("=")
+		sb.WriteString(quoteString(properties[k]))
+		if i < len(keys)-1 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n")
+	}
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func quoteString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
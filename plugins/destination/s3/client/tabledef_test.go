@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/cloudquery/filetypes"
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+var testTableDefinitionTable = &schema.Table{
+	Name: "test_table",
+	Columns: schema.ColumnList{
+		{Name: "id", Type: schema.TypeInt, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+		{Name: "name", Type: schema.TypeString},
+		{Name: "created_at", Type: schema.TypeTimestamp},
+		{Name: "tags", Type: schema.TypeJSON},
+		{Name: "ips", Type: schema.TypeInetArray},
+		{Name: "data", Type: schema.TypeByteArray},
+	},
+}
+
+func TestTableDefinitionDDL(t *testing.T) {
+	cases := []struct {
+		spec     Spec
+		database string
+		want     string
+	}{
+		{
+			spec: Spec{
+				Bucket:   "test-bucket",
+				Path:     "data/{{TABLE}}/year={{YEAR}}/month={{MONTH}}/{{SOURCE}}/{{UUID}}.parquet",
+				FileSpec: &filetypes.FileSpec{Format: filetypes.FormatTypeParquet},
+			},
+			database: "cloudquery",
+			want: "CREATE EXTERNAL TABLE IF NOT EXISTS `cloudquery`.`test_table` (\n" +
+				"  `id` bigint,\n" +
+				"  `name` string,\n" +
+				"  `created_at` timestamp,\n" +
+				"  `tags` string,\n" +
+				"  `ips` array<string>,\n" +
+				"  `data` binary\n" +
+				")\n" +
+				"PARTITIONED BY (\n" +
+				"  `cq_year` int,\n" +
+				"  `cq_month` int,\n" +
+				"  `cq_source` string\n" +
+				")\n" +
+				"ROW FORMAT SERDE 'org.apache.hadoop.hive.ql.io.parquet.serde.ParquetHiveSerDe'\n" +
+				"STORED AS INPUTFORMAT 'org.apache.hadoop.hive.ql.io.parquet.MapredParquetInputFormat'\n" +
+				"OUTPUTFORMAT 'org.apache.hadoop.hive.ql.io.parquet.MapredParquetOutputFormat'\n" +
+				"LOCATION 's3://test-bucket/data/test_table/'\n" +
+				"TBLPROPERTIES (\n" +
+				"  'classification'='parquet',\n" +
+				"  'projection.cq_month.digits'='2',\n" +
+				"  'projection.cq_month.range'='1,12',\n" +
+				"  'projection.cq_month.type'='integer',\n" +
+				"  'projection.cq_source.type'='injected',\n" +
+				"  'projection.cq_year.range'='2020,2100',\n" +
+				"  'projection.cq_year.type'='integer',\n" +
+				"  'projection.enabled'='true',\n" +
+				"  'storage.location.template'='s3://test-bucket/data/test_table/year=${cq_year}/month=${cq_month}/${cq_source}/'\n" +
+				");\n",
+		},
+		{
+			spec: Spec{
+				Bucket:   "test-bucket",
+				Path:     "{{TABLE}}/{{UUID}}.csv",
+				FileSpec: &filetypes.FileSpec{Format: filetypes.FormatTypeCSV, FormatSpec: map[string]any{"delimiter": "\t"}},
+			},
+			want: "CREATE EXTERNAL TABLE IF NOT EXISTS `test_table` (\n" +
+				"  `id` string,\n" +
+				"  `name` string,\n" +
+				"  `created_at` string,\n" +
+				"  `tags` string,\n" +
+				"  `ips` string,\n" +
+				"  `data` string\n" +
+				")\n" +
+				"ROW FORMAT SERDE 'org.apache.hadoop.hive.serde2.OpenCSVSerde'\n" +
+				"WITH SERDEPROPERTIES (\n" +
+				"  'quoteChar'='\"',\n" +
+				"  'separatorChar'='\t'\n" +
+				")\n" +
+				"STORED AS INPUTFORMAT 'org.apache.hadoop.mapred.TextInputFormat'\n" +
+				"OUTPUTFORMAT 'org.apache.hadoop.hive.ql.io.HiveIgnoreKeyTextOutputFormat'\n" +
+				"LOCATION 's3://test-bucket/test_table/'\n" +
+				"TBLPROPERTIES (\n" +
+				"  'classification'='csv',\n" +
+				"  'skip.header.line.count'='1'\n" +
+				");\n",
+		},
+	}
+
+	for _, tc := range cases {
+		def, err := newTableDefinition(&tc.spec, testTableDefinitionTable)
+		require.NoError(t, err)
+		if diff := cmp.Diff(tc.want, def.ddl(tc.database)); diff != "" {
+			t.Errorf("unexpected DDL for %s (-want +got):\n%s", tc.spec.Path, diff)
+		}
+	}
+}
+
+func TestTableDefinitionJSONTypes(t *testing.T) {
+	spec := Spec{Bucket: "test-bucket", Path: "{{TABLE}}/{{UUID}}.json", FileSpec: &filetypes.FileSpec{Format: filetypes.FormatTypeJSON}}
+	def, err := newTableDefinition(&spec, testTableDefinitionTable)
+	require.NoError(t, err)
+	want := []tableColumn{
+		{name: "id", typ: "bigint"},
+		{name: "name", typ: "string"},
+		{name: "created_at", typ: "timestamp"},
+		{name: "tags", typ: "string"},
+		{name: "ips", typ: "array<string>"},
+		{name: "data", typ: "string"},
+	}
+	if diff := cmp.Diff(want, def.columns, cmp.AllowUnexported(tableColumn{})); diff != "" {
+		t.Errorf("unexpected columns (-want +got):\n%s", diff)
+	}
+	require.Equal(t, "org.openx.data.jsonserde.JsonSerDe", def.serde)
+}
+
+func TestValidateTableDefinitionPath(t *testing.T) {
+	cases := []struct {
+		path    string
+		wantErr bool
+	}{
+		{path: "data/{{TABLE}}/{{UUID}}.parquet"},
+		{path: "data/{{YEAR}}/{{TABLE}}/{{SYNC_ID}}/{{UUID}}.parquet"},
+		{path: "data/{{TABLE}}.parquet.{{UUID}}", wantErr: true},      // objects of all tables in one directory
+		{path: "data/{{TABLE}}/{{UUID}}/data.parquet", wantErr: true}, // every object in its own directory
+	}
+	for _, tc := range cases {
+		err := validateTableDefinitionPath(tc.path)
+		if tc.wantErr {
+			require.Error(t, err, tc.path)
+		} else {
+			require.NoError(t, err, tc.path)
+		}
+	}
+}
+
+func TestMigrateDDLFile(t *testing.T) {
+	ddlFile := t.TempDir() + "/tables.sql"
+	c := &Client{pluginSpec: Spec{
+		Bucket:   "test-bucket",
+		Path:     "{{TABLE}}/{{UUID}}.json",
+		FileSpec: &filetypes.FileSpec{Format: filetypes.FormatTypeJSON},
+		DDLFile:  ddlFile,
+	}}
+	tables := schema.Tables{
+		{Name: "test_parent", Columns: schema.ColumnList{{Name: "id", Type: schema.TypeInt}}, Relations: schema.Tables{
+			{Name: "test_child", Columns: schema.ColumnList{{Name: "id", Type: schema.TypeInt}}},
+		}},
+	}
+	require.NoError(t, c.Migrate(context.Background(), tables))
+
+	b, err := os.ReadFile(ddlFile)
+	require.NoError(t, err)
+	require.Contains(t, string(b), "CREATE EXTERNAL TABLE IF NOT EXISTS `test_parent`")
+	require.Contains(t, string(b), "CREATE EXTERNAL TABLE IF NOT EXISTS `test_child`")
+}
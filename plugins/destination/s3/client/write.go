@@ -12,26 +12,35 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/cloudquery/cloudquery/plugins/destination/internal/manifest"
 	"github.com/cloudquery/plugin-sdk/schema"
 	"github.com/google/uuid"
 )
 
 const (
-	PathVarTable = "{{TABLE}}"
-	PathVarUUID  = "{{UUID}}"
-	YearVar      = "{{YEAR}}"
-	MonthVar     = "{{MONTH}}"
-	DayVar       = "{{DAY}}"
-	HourVar      = "{{HOUR}}"
-	MinuteVar    = "{{MINUTE}}"
+	PathVarTable  = "{{TABLE}}"
+	PathVarUUID   = "{{UUID}}"
+	PathVarSource = "{{SOURCE}}"
+	PathVarSyncID = "{{SYNC_ID}}"
+	YearVar       = "{{YEAR}}"
+	MonthVar      = "{{MONTH}}"
+	DayVar        = "{{DAY}}"
+	HourVar       = "{{HOUR}}"
+	MinuteVar     = "{{MINUTE}}"
 )
 
 var reInvalidJSONKey = regexp.MustCompile(`\W`)
 
-func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, data [][]any) error {
+func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, data [][]any) (err error) {
 	if len(data) == 0 {
 		return nil
 	}
+	defer func() {
+		// the sync continues after a failed write, so the objects of the directories written to are incomplete
+		if err != nil && c.manifests != nil {
+			c.manifests.WriteFailed()
+		}
+	}()
 
 	if c.pluginSpec.Athena {
 		for _, resource := range data {
@@ -44,23 +53,28 @@ func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, data
 		}
 	}
 
-	var b bytes.Buffer
-	w := io.Writer(&b)
-
 	timeNow := time.Now().UTC()
 
-	if err := c.Client.WriteTableBatchFile(w, table, data); err != nil {
-		return err
-	}
-	// we don't upload in parallel here because AWS sdk moves the burden to the developer, and
-	// we don't want to deal with that yet. in the future maybe we can run some benchmarks and see if adding parallelization helps.
-	r := io.Reader(&b)
-	if _, err := c.uploader.Upload(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(c.pluginSpec.Bucket),
-		Key:    aws.String(replacePathVariables(c.pluginSpec.Path, table.Name, uuid.NewString(), timeNow)),
-		Body:   r,
-	}); err != nil {
-		return err
+	for _, batch := range manifest.SplitBySync(c.pluginSpec.Path, table, data) {
+		var b bytes.Buffer
+		w := io.Writer(&b)
+		if err := c.Client.WriteTableBatchFile(w, table, batch.Data); err != nil {
+			return err
+		}
+		// we don't upload in parallel here because AWS sdk moves the burden to the developer, and
+		// we don't want to deal with that yet. in the future maybe we can run some benchmarks and see if adding parallelization helps.
+		key := replacePathVariables(c.pluginSpec.Path, table.Name, batch.Source, batch.SyncID, uuid.NewString(), timeNow)
+		r := io.Reader(&b)
+		if _, err := c.uploader.Upload(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(c.pluginSpec.Bucket),
+			Key:    aws.String(key),
+			Body:   r,
+		}); err != nil {
+			return err
+		}
+		if c.manifests != nil {
+			c.manifests.Add(key, table, batch.Source, batch.SyncID, len(batch.Data))
+		}
 	}
 
 	return nil
@@ -91,8 +105,10 @@ func sanitizeJSONKeys(obj any) {
 	}
 }
 
-func replacePathVariables(specPath, table, fileIdentifier string, t time.Time) string {
+func replacePathVariables(specPath, table, source, syncID, fileIdentifier string, t time.Time) string {
 	name := strings.ReplaceAll(specPath, PathVarTable, table)
+	name = strings.ReplaceAll(name, PathVarSource, source)
+	name = strings.ReplaceAll(name, PathVarSyncID, syncID)
 	name = strings.ReplaceAll(name, PathVarUUID, fileIdentifier)
 	name = strings.ReplaceAll(name, YearVar, t.Format("2006"))
 	name = strings.ReplaceAll(name, MonthVar, t.Format("01"))
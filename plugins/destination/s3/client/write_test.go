@@ -53,6 +53,8 @@ func TestReplacePathVariables(t *testing.T) {
 		inputPath    string
 		uuid         string
 		tableName    string
+		source       string
+		syncID       string
 		expectedPath string
 	}{
 		{
@@ -85,11 +87,19 @@ func TestReplacePathVariables(t *testing.T) {
 			uuid:         "FAKE-UUID",
 			expectedPath: "test/test/test-table/year=2021/month=03/day=05/hour=04/minute=01/FAKE-UUID.json",
 		},
+		{
+			inputPath:    "test/test/{{TABLE}}/source={{SOURCE}}/sync_id={{SYNC_ID}}/{{UUID}}.json",
+			tableName:    "test-table",
+			source:       "test-source",
+			syncID:       "20210305T040102Z",
+			uuid:         "FAKE-UUID",
+			expectedPath: "test/test/test-table/source=test-source/sync_id=20210305T040102Z/FAKE-UUID.json",
+		},
 	}
 
 	tm := time.Date(2021, 3, 5, 4, 1, 2, 3, time.UTC)
 	for _, tc := range cases {
-		if diff := cmp.Diff(tc.expectedPath, replacePathVariables(tc.inputPath, tc.tableName, tc.uuid, tm)); diff != "" {
+		if diff := cmp.Diff(tc.expectedPath, replacePathVariables(tc.inputPath, tc.tableName, tc.source, tc.syncID, tc.uuid, tm)); diff != "" {
 			t.Errorf("unexpected Path Substitution (-want +got):\n%s", diff)
 		}
 	}
@@ -6,7 +6,9 @@ require (
 	github.com/aws/aws-sdk-go-v2 v1.17.7
 	github.com/aws/aws-sdk-go-v2/config v1.18.19
 	github.com/aws/aws-sdk-go-v2/feature/s3/manager v1.11.60
+	github.com/aws/aws-sdk-go-v2/service/glue v1.45.0
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.31.1
+	github.com/cloudquery/cloudquery/plugins/destination/internal/manifest v0.0.0-00010101000000-000000000000
 	github.com/cloudquery/filetypes v1.6.2
 	github.com/cloudquery/plugin-sdk v1.44.2
 	github.com/google/go-cmp v0.5.9
@@ -15,6 +17,8 @@ require (
 	github.com/stretchr/testify v1.8.2
 )
 
+replace github.com/cloudquery/cloudquery/plugins/destination/internal/manifest => ../internal/manifest
+
 replace github.com/apache/arrow/go/v12 => github.com/cloudquery/arrow/go/v12 v12.0.0-20230317130341-c648117570af
 
 require (
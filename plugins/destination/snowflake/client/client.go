@@ -16,22 +16,20 @@ import (
 type Client struct {
 	destination.UnimplementedUnmanagedWriter
 	destination.DefaultReverseTransformer
-	db      *sql.DB
-	logger  zerolog.Logger
-	spec    specs.Destination
-	metrics destination.Metrics
+	db         *sql.DB
+	logger     zerolog.Logger
+	spec       specs.Destination
+	pluginSpec Spec
+	metrics    destination.Metrics
 }
 
 func New(ctx context.Context, logger zerolog.Logger, destSpec specs.Destination) (destination.Client, error) {
-	if destSpec.WriteMode != specs.WriteModeAppend {
-		return nil, fmt.Errorf("snowflake destination only supports append mode")
-	}
 	c := &Client{
 		logger: logger.With().Str("module", "sf-dest").Logger(),
 	}
-	var spec Spec
+	spec := &c.pluginSpec
 	c.spec = destSpec
-	if err := destSpec.UnmarshalSpec(&spec); err != nil {
+	if err := destSpec.UnmarshalSpec(spec); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal snowflake spec: %w", err)
 	}
 	spec.SetDefaults()
@@ -47,11 +45,13 @@ func New(ctx context.Context, logger zerolog.Logger, destSpec specs.Destination)
 		return nil, err
 	}
 	c.db = db
-	if _, err := c.db.ExecContext(ctx, createOrReplaceFileFormat); err != nil {
-		return nil, fmt.Errorf("failed to create file format %s: %w", createOrReplaceFileFormat, err)
+	query := fmt.Sprintf(createOrReplaceFileFormat, spec.FileFormat, spec.fileFormatCompression())
+	if _, err := c.db.ExecContext(ctx, query); err != nil {
+		return nil, fmt.Errorf("failed to create file format %s: %w", query, err)
 	}
-	if _, err := c.db.ExecContext(ctx, createOrReplaceStage); err != nil {
-		return nil, fmt.Errorf("failed to create stage %s: %w", createOrReplaceStage, err)
+	query = fmt.Sprintf(createOrReplaceStage, spec.StageName, spec.FileFormat)
+	if _, err := c.db.ExecContext(ctx, query); err != nil {
+		return nil, fmt.Errorf("failed to create stage %s: %w", query, err)
 	}
 	return c, nil
 }
@@ -19,7 +19,6 @@ func TestPlugin(t *testing.T) {
 			},
 		},
 		destination.PluginTestSuiteTests{
-			SkipOverwrite:             true,
 			SkipMigrateAppend:         true, // fails with `invalid identifier '"new_column"'`, maybe because delays in schema propagation?
 			SkipMigrateOverwrite:      true,
 			SkipMigrateOverwriteForce: true,
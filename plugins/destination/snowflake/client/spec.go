@@ -1,18 +1,72 @@
 package client
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+)
+
+type CompressionOption string
+
+const (
+	CompressionOptionGzip CompressionOption = "gzip"
+	CompressionOptionNone CompressionOption = "none"
+
+	defaultStageName  = "cq_plugin_stage"
+	defaultFileFormat = "cq_plugin_json_format"
+)
+
+var (
+	compressionOptions = []CompressionOption{CompressionOptionGzip, CompressionOptionNone}
+	// reObjectName matches unquoted, optionally qualified, object names, e.g. db.schema.stage
+	reObjectName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*){0,2}$`)
+)
+
+func (c CompressionOption) Validate() error {
+	for _, option := range compressionOptions {
+		if option == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v is not a valid option for compression. Options are: %v", c, compressionOptions)
+}
 
 type Spec struct {
-	ConnectionString string `json:"connection_string,omitempty"`
+	ConnectionString string            `json:"connection_string,omitempty"`
+	StageName        string            `json:"stage_name,omitempty"`
+	FileFormat       string            `json:"file_format,omitempty"`
+	Compression      CompressionOption `json:"compression,omitempty"`
 }
 
-func (*Spec) SetDefaults() {
-	// stub for any future defaults
+func (s *Spec) SetDefaults() {
+	if s.StageName == "" {
+		s.StageName = defaultStageName
+	}
+	if s.FileFormat == "" {
+		s.FileFormat = defaultFileFormat
+	}
+	if s.Compression == "" {
+		s.Compression = CompressionOptionGzip
+	}
 }
 
 func (s *Spec) Validate() error {
 	if s.ConnectionString == "" {
 		return fmt.Errorf("connection_string is required")
 	}
-	return nil
+	if !reObjectName.MatchString(s.StageName) {
+		return fmt.Errorf("stage_name %q is not a valid Snowflake object name", s.StageName)
+	}
+	if !reObjectName.MatchString(s.FileFormat) {
+		return fmt.Errorf("file_format %q is not a valid Snowflake object name", s.FileFormat)
+	}
+	return s.Compression.Validate()
+}
+
+// fileFormatCompression is the compression of the staged files in the file format. Snowflake detects gzip
+// compressed files when it's AUTO.
+func (s *Spec) fileFormatCompression() string {
+	if s.Compression == CompressionOptionNone {
+		return "NONE"
+	}
+	return "AUTO"
 }
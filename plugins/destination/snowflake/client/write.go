@@ -6,22 +6,65 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/google/uuid"
 )
 
 const (
-	createOrReplaceFileFormat = `create or replace file format cq_plugin_json_format type = 'JSON'`
-	createOrReplaceStage      = `create or replace stage cq_plugin_stage file_format = cq_plugin_json_format;`
-	putFileIntoStage          = `put file://%s @cq_plugin_stage auto_compress=true`
-	copyIntoTable             = `copy into %s from @cq_plugin_stage/%s file_format = (format_name = cq_plugin_json_format) match_by_column_name = case_insensitive`
+	createOrReplaceFileFormat = `create or replace file format %s type = 'JSON' compression = %s`
+	createOrReplaceStage      = `create or replace stage %s file_format = %s;`
+	putFileIntoStage          = `put file://%s @%s auto_compress=%t`
+	copyIntoTable             = `copy into %s from @%s/%s file_format = (format_name = %s) match_by_column_name = case_insensitive`
+	createStagingTable        = `create transient table %s like %s`
+	dropStagingTable          = `drop table if exists %s`
+
+	stagingTablePrefix = "cq_staging_"
 )
 
+// WriteTableBatch puts the resources into the stage and copies them into the table. In overwrite mode, tables with
+// a primary key are copied into a transient staging table instead, and merged into the table on the primary key.
 func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, resources [][]any) error {
-	f, err := os.CreateTemp(os.TempDir(), table.Name+".json.*")
+	if c.spec.WriteMode == specs.WriteModeAppend || len(table.PrimaryKeys()) == 0 {
+		file, err := c.putIntoStage(ctx, table, resources)
+		if err != nil {
+			return err
+		}
+		return c.copyIntoTable(ctx, table.Name, file)
+	}
+
+	file, err := c.putIntoStage(ctx, table, lastByPrimaryKey(table, resources))
 	if err != nil {
 		return err
 	}
+	staging := stagingTableName(table.Name)
+	sql := fmt.Sprintf(createStagingTable, staging, table.Name)
+	if _, err := c.db.ExecContext(ctx, sql); err != nil {
+		return fmt.Errorf("failed to create staging table %s: %w", sql, err)
+	}
+	defer func() {
+		if _, err := c.db.ExecContext(ctx, fmt.Sprintf(dropStagingTable, staging)); err != nil {
+			c.logger.Warn().Err(err).Str("table", staging).Msg("Failed to drop staging table")
+		}
+	}()
+	if err := c.copyIntoTable(ctx, staging, file); err != nil {
+		return err
+	}
+	sql = mergeIntoTable(table, staging)
+	if _, err := c.db.ExecContext(ctx, sql); err != nil {
+		return fmt.Errorf("failed to merge staging table into table with %s: %w", sql, err)
+	}
+	return nil
+}
+
+// putIntoStage writes the resources to a JSON file and puts it into the stage, returning the name of the file
+func (c *Client) putIntoStage(ctx context.Context, table *schema.Table, resources [][]any) (string, error) {
+	f, err := os.CreateTemp(os.TempDir(), table.Name+".json.*")
+	if err != nil {
+		return "", err
+	}
 	defer func() {
 		f.Close()
 		os.Remove(f.Name())
@@ -35,24 +78,105 @@ func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, resou
 
 		b, err := json.Marshal(jsonObj)
 		if err != nil {
-			return err
+			return "", err
 		}
 		b = append(b, '\n')
 		if _, err := f.Write(b); err != nil {
-			return err
+			return "", err
 		}
 	}
 
 	if err := f.Close(); err != nil {
-		return fmt.Errorf("failed to close temp file with last resource %s: %w", f.Name(), err)
+		return "", fmt.Errorf("failed to close temp file with last resource %s: %w", f.Name(), err)
 	}
-	sql := fmt.Sprintf(putFileIntoStage, f.Name())
+	sql := fmt.Sprintf(putFileIntoStage, f.Name(), c.pluginSpec.StageName, c.pluginSpec.Compression == CompressionOptionGzip)
 	if _, err := c.db.ExecContext(ctx, sql); err != nil {
-		return fmt.Errorf("failed to put file into stage with last resource %s: %w", sql, err)
+		return "", fmt.Errorf("failed to put file into stage with last resource %s: %w", sql, err)
 	}
-	sql = fmt.Sprintf(copyIntoTable, table.Name, path.Base(f.Name()))
+	return path.Base(f.Name()), nil
+}
+
+func (c *Client) copyIntoTable(ctx context.Context, tableName string, file string) error {
+	sql := fmt.Sprintf(copyIntoTable, tableName, c.pluginSpec.StageName, file, c.pluginSpec.FileFormat)
 	if _, err := c.db.ExecContext(ctx, sql); err != nil {
 		return fmt.Errorf("failed to copy file into table with last resource %s: %w", sql, err)
 	}
-	return err
+	return nil
+}
+
+// stagingTableName returns a unique name for the staging table of a batch, so that concurrent batches of the
+// same table don't share it
+func stagingTableName(tableName string) string {
+	return stagingTablePrefix + tableName + "_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+// lastByPrimaryKey returns the last resource of every primary key, as MERGE fails when more than one row of the
+// source matches a row of the target
+func lastByPrimaryKey(table *schema.Table, resources [][]any) [][]any {
+	var pkIndexes []int
+	for i, column := range table.Columns {
+		if column.CreationOptions.PrimaryKey {
+			pkIndexes = append(pkIndexes, i)
+		}
+	}
+	last := make(map[string]int, len(resources))
+	keys := make([]string, len(resources))
+	for i, r := range resources {
+		pk := make([]any, len(pkIndexes))
+		for j, index := range pkIndexes {
+			pk[j] = r[index]
+		}
+		b, _ := json.Marshal(pk)
+		keys[i] = string(b)
+		last[keys[i]] = i
+	}
+	if len(last) == len(resources) {
+		return resources
+	}
+	deduped := make([][]any, 0, len(last))
+	for i, r := range resources {
+		if last[keys[i]] == i {
+			deduped = append(deduped, r)
+		}
+	}
+	return deduped
+}
+
+// mergeIntoTable returns the statement merging the staging table into the table on its primary key
+func mergeIntoTable(table *schema.Table, staging string) string {
+	var sb strings.Builder
+	sb.WriteString("merge into ")
+	sb.WriteString(table.Name)
+	sb.WriteString(" t using ")
+	sb.WriteString(staging)
+	sb.WriteString(" s on ")
+	for i, pk := range table.PrimaryKeys() {
+		if i > 0 {
+			sb.WriteString(" and ")
+		}
+		sb.WriteString(`t."` + pk + `" = s."` + pk + `"`)
+	}
+	sb.WriteString(" when matched then update set ")
+	for i, column := range table.Columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(`t."` + column.Name + `" = s."` + column.Name + `"`)
+	}
+	sb.WriteString(" when not matched then insert (")
+	for i, column := range table.Columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(`"` + column.Name + `"`)
+	}
+	sb.WriteString(") values (")
+	for i, column := range table.Columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(`s."` + column.Name + `"`)
+	}
+	sb.WriteString(")")
+	return sb.String()
 }
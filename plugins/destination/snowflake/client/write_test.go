@@ -0,0 +1,54 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/stretchr/testify/require"
+)
+
+var testMergeTable = &schema.Table{
+	Name: "test_table",
+	Columns: schema.ColumnList{
+		{Name: "id", Type: schema.TypeInt, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+		{Name: "region", Type: schema.TypeString, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+		{Name: "name", Type: schema.TypeString},
+	},
+}
+
+func TestMergeIntoTable(t *testing.T) {
+	want := `merge into test_table t using cq_staging_test_table s on t."id" = s."id" and t."region" = s."region" ` +
+		`when matched then update set t."id" = s."id", t."region" = s."region", t."name" = s."name" ` +
+		`when not matched then insert ("id", "region", "name") values (s."id", s."region", s."name")`
+	require.Equal(t, want, mergeIntoTable(testMergeTable, "cq_staging_test_table"))
+}
+
+func TestLastByPrimaryKey(t *testing.T) {
+	resources := [][]any{
+		{int64(1), "us-east-1", "first"},
+		{int64(1), "us-west-2", "second"},
+		{int64(1), "us-east-1", "third"},
+	}
+	require.Equal(t, [][]any{resources[1], resources[2]}, lastByPrimaryKey(testMergeTable, resources))
+	require.Equal(t, resources[:2], lastByPrimaryKey(testMergeTable, resources[:2]))
+}
+
+func TestSpec(t *testing.T) {
+	spec := Spec{ConnectionString: "user:pass@account/db"}
+	spec.SetDefaults()
+	require.NoError(t, spec.Validate())
+	require.Equal(t, "cq_plugin_stage", spec.StageName)
+	require.Equal(t, "AUTO", spec.fileFormatCompression())
+
+	spec.StageName = "db.schema.my_stage"
+	spec.Compression = CompressionOptionNone
+	require.NoError(t, spec.Validate())
+	require.Equal(t, "NONE", spec.fileFormatCompression())
+
+	spec.StageName = "my stage; drop table x"
+	require.Error(t, spec.Validate())
+
+	spec.StageName = defaultStageName
+	spec.Compression = "zstd"
+	require.Error(t, spec.Validate())
+}
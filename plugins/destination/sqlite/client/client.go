@@ -15,12 +15,11 @@ import (
 )
 
 type Client struct {
-	destination.UnimplementedManagedWriter
+	destination.UnimplementedUnmanagedWriter
 	destination.DefaultReverseTransformer
-	db      *sql.DB
-	logger  zerolog.Logger
-	spec    specs.Destination
-	metrics destination.Metrics
+	db     *sql.DB
+	logger zerolog.Logger
+	spec   specs.Destination
 }
 
 func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (destination.Client, error) {
@@ -39,6 +38,8 @@ func New(ctx context.Context, logger zerolog.Logger, spec specs.Destination) (de
 	if err != nil {
 		return nil, err
 	}
+	// the managed writer writes the batches of every table concurrently, and SQLite only allows a single writer
+	db.SetMaxOpenConns(1)
 	c.db = db
 	return c, nil
 }
@@ -18,7 +18,7 @@ var migrateStrategy = destination.MigrateStrategy{
 func TestPlugin(t *testing.T) {
 	destination.PluginTestSuiteRunner(t,
 		func() *destination.Plugin {
-			return destination.NewPlugin("sqlite", "development", New)
+			return destination.NewPlugin("sqlite", "development", New, destination.WithManagedWriter())
 		},
 		specs.Destination{
 			Spec: &Spec{
@@ -2,28 +2,46 @@ package client
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
 
-	"github.com/cloudquery/plugin-sdk/plugins/destination"
 	"github.com/cloudquery/plugin-sdk/schema"
 	"github.com/cloudquery/plugin-sdk/specs"
 )
 
-func (c *Client) Write(ctx context.Context, tables schema.Tables, res <-chan *destination.ClientResource) error {
-	var sql string
-	for r := range res {
-		table := tables.Get(r.TableName)
-		if c.spec.WriteMode == specs.WriteModeAppend {
-			sql = c.insert(table)
-		} else {
-			sql = c.upsert(table)
-		}
-		if _, err := c.db.Exec(sql, r.Data...); err != nil {
-			return fmt.Errorf("failed to execute '%s': %w", sql, err)
+// WriteTableBatch writes the resources with a prepared statement in a transaction
+func (c *Client) WriteTableBatch(ctx context.Context, table *schema.Table, resources [][]any) error {
+	var query string
+	if c.spec.WriteMode == specs.WriteModeAppend {
+		query = c.insert(table)
+	} else {
+		query = c.upsert(table)
+	}
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := execBatch(ctx, tx, query, resources); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			c.logger.Warn().Err(rollbackErr).Msg("Failed to rollback transaction")
 		}
+		return err
 	}
+	return tx.Commit()
+}
 
+func execBatch(ctx context.Context, tx *sql.Tx, query string, resources [][]any) error {
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare '%s': %w", query, err)
+	}
+	defer stmt.Close()
+	for _, r := range resources {
+		if _, err := stmt.ExecContext(ctx, r...); err != nil {
+			return fmt.Errorf("failed to execute '%s': %w", query, err)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudquery/plugin-sdk/schema"
+	"github.com/cloudquery/plugin-sdk/specs"
+	"github.com/rs/zerolog"
+)
+
+func TestWriteTableBatchUpsert(t *testing.T) {
+	ctx := context.Background()
+	spec := &Spec{ConnectionString: filepath.Join(t.TempDir(), "test.db")}
+	client, err := New(ctx, zerolog.Nop(), specs.Destination{WriteMode: specs.WriteModeOverwrite, Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close(ctx)
+	c := client.(*Client)
+
+	table := &schema.Table{Name: "test_table", Columns: schema.ColumnList{
+		{Name: "id", Type: schema.TypeInt, CreationOptions: schema.ColumnCreationOptions{PrimaryKey: true}},
+		{Name: "name", Type: schema.TypeString},
+	}}
+	if err := c.Migrate(ctx, schema.Tables{table}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.WriteTableBatch(ctx, table, [][]any{{int64(1), "a"}, {int64(2), "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteTableBatch(ctx, table, [][]any{{int64(1), "c"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := c.db.QueryRow(`select count(*) from "test_table"`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 rows, got %d", count)
+	}
+	var name string
+	if err := c.db.QueryRow(`select "name" from "test_table" where "id" = 1`).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "c" {
+		t.Errorf("expected the row to be replaced, got name %q", name)
+	}
+}